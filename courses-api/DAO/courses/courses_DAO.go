@@ -1,5 +1,10 @@
 package courses
 
+import "errors"
+
+// ErrNotFound se devuelve cuando no existe un curso con el ID buscado
+var ErrNotFound = errors.New("course not found")
+
 type Course struct {
 	ID           int64   `bson:"id"`
 	Name         string  `bson:"name"`
@@ -10,4 +15,20 @@ type Course struct {
 	ImageID      string  `bson:"image_id"`
 	Capacity     int     `bson:"capacity"`
 	Rating       float64 `bson:"rating"`
+	CommentCount int     `bson:"comment_count"`
+	CreatedAt    int64   `bson:"created_at"`
+	Version      int64   `bson:"version,omitempty"` // Se incrementa en cada escritura
+}
+
+// CoursesFilter criterios de búsqueda y paginación sobre la colección de cursos
+type CoursesFilter struct {
+	Category     string
+	InstructorID int64
+	MinRating    float64
+	MinCapacity  int
+	SortField    string
+	SortOrder    int   // 1 ascendente, -1 descendente
+	AfterID      int64 // Solo cursos con ID mayor, para paginar por clave
+	Skip         int64
+	Limit        int64
 }
@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	coursesDAO "courses-api/DAO/courses"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Estados de un evento del outbox
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed" // Agotó sus intentos de publicación, el relay no lo vuelve a tomar
+)
+
+// Event evento de curso pendiente de publicar en RabbitMQ
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Operation string             `bson:"operation"` // events.Operation del evento a publicar
+	CourseID  int64              `bson:"course_id"`
+	// Estado del curso después del cambio, publicado en el evento. Vacío en DELETE
+	// y en los eventos registrados antes de incluirlo.
+	Course    *coursesDAO.Course `bson:"course,omitempty"`
+	Status    string             `bson:"status"`
+	Attempts  int                `bson:"attempts"`
+	LastError string             `bson:"last_error,omitempty"`
+	CreatedAt int64              `bson:"created_at"`
+	SentAt    int64              `bson:"sent_at,omitempty"`
+	// Hasta cuándo el evento está reservado por una instancia del relay; vencido
+	// ese plazo otra instancia puede tomarlo
+	ClaimedUntil int64 `bson:"claimed_until,omitempty"`
+}
@@ -0,0 +1,7 @@
+package sequences
+
+// Counter documento de la colección de contadores, uno por secuencia
+type Counter struct {
+	Name string `bson:"_id"`
+	Seq  int64  `bson:"seq"`
+}
@@ -27,8 +27,9 @@ type Inscription struct {
 	CourseID uint `json:"course_id"`
 }
 
+// GetInscriptionsByCourse devuelve las inscripciones pendientes y activas del curso
 func (c *HTTPClient) GetInscriptionsByCourse(courseID uint) ([]Inscription, error) {
-	url := fmt.Sprintf("%s/courses/%d/inscriptions", c.inscriptionsAPIURL, courseID)
+	url := fmt.Sprintf("%s/courses/%d/inscriptions?status=pending,active", c.inscriptionsAPIURL, courseID)
 	resp, err := c.client.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("error making request to inscriptions API: %v", err)
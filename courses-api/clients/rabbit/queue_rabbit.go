@@ -1,65 +1,138 @@
 package rabbit
 
 import (
-	"courses-api/domain/courses"
-	"encoding/json"
+	"errors"
+	"events"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
 
+// Tiempo máximo de espera de la confirmación del broker para cada mensaje
+const confirmTimeout = 5 * time.Second
+
 type RabbitConfig struct {
-	URI       string
-	QueueName string
+	URI      string
+	Topology events.Topology
 }
 
+// Rabbit publica eventos de cursos con confirmación del broker. Si la conexión o
+// el canal se pierden, el siguiente Publish vuelve a conectarse.
 type Rabbit struct {
-	Connection *amqp.Connection
-	Channel    *amqp.Channel
-	Queue      amqp.Queue
+	config     RabbitConfig
+	mu         sync.Mutex
+	connection *amqp.Connection
+	channel    *amqp.Channel
+	confirms   chan amqp.Confirmation
 }
 
-func NewRabbit(config RabbitConfig) Rabbit {
-	connection, err := amqp.Dial(config.URI)
+func NewRabbit(config RabbitConfig) (*Rabbit, error) {
+	r := &Rabbit{config: config}
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// connect abre la conexión y el canal en modo confirmación y declara la topología.
+// Debe llamarse con el mutex tomado (o antes de compartir la instancia).
+func (r *Rabbit) connect() error {
+	connection, err := amqp.Dial(r.config.URI)
 	if err != nil {
-		log.Fatalf("error getting Rabbit connection: %v", err)
+		return fmt.Errorf("error getting Rabbit connection: %w", err)
 	}
 	channel, err := connection.Channel()
 	if err != nil {
-		log.Fatalf("error creating Rabbit channel: %v", err)
+		connection.Close()
+		return fmt.Errorf("error creating Rabbit channel: %w", err)
 	}
-	queue, err := channel.QueueDeclare(config.QueueName, false, false, false, false, nil)
-	if err != nil {
-		log.Fatalf("error declaring Rabbit queue: %v", err)
+	if err := r.config.Topology.Declare(channel); err != nil {
+		connection.Close()
+		return fmt.Errorf("error declaring Rabbit topology: %w", err)
 	}
-	return Rabbit{
-		Connection: connection,
-		Channel:    channel,
-		Queue:      queue,
+	if err := channel.Confirm(false); err != nil {
+		connection.Close()
+		return fmt.Errorf("error enabling Rabbit publisher confirms: %w", err)
+	}
+
+	r.connection = connection
+	r.channel = channel
+	r.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// reset descarta la conexión actual para que el próximo Publish se reconecte
+func (r *Rabbit) reset() {
+	if r.connection != nil {
+		r.connection.Close()
 	}
+	r.connection = nil
+	r.channel = nil
+	r.confirms = nil
 }
 
-func (r Rabbit) Publish(cursoNew courses.CursosNew) error {
-	bytes, err := json.Marshal(cursoNew)
+// Publish publica el evento como mensaje persistente y espera la confirmación del broker
+func (r *Rabbit) Publish(event events.CourseEvent) error {
+	bytes, err := events.Encode(event)
 	if err != nil {
-		log.Printf("Error al serializar CursosNew: %v", err)
-		return fmt.Errorf("error al serializar CursosNew: %w", err)
+		log.Printf("Error al serializar CourseEvent: %v", err)
+		return fmt.Errorf("error al serializar CourseEvent: %w", err)
 	}
 	log.Println("Mensaje serializado:", string(bytes))
 
-	if err := r.Channel.Publish(
-		"",
-		r.Queue.Name,
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.connection == nil || r.connection.IsClosed() {
+		log.Println("Reconectando a RabbitMQ...")
+		r.reset()
+		if err := r.connect(); err != nil {
+			return fmt.Errorf("error al reconectar con Rabbit: %w", err)
+		}
+	}
+
+	if err := r.channel.Publish(
+		r.config.Topology.Exchange,
+		r.config.Topology.RoutingKey,
 		false,
 		false,
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        bytes,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    event.EventID,
+			Timestamp:    event.Timestamp,
+			Body:         bytes,
 		}); err != nil {
 		log.Printf("Error al publicar en Rabbit: %v", err)
+		r.reset()
 		return fmt.Errorf("error al publicar en Rabbit: %w", err)
 	}
+
+	select {
+	case confirmation, ok := <-r.confirms:
+		if !ok {
+			r.reset()
+			return errors.New("el canal de Rabbit se cerró antes de confirmar el mensaje")
+		}
+		if !confirmation.Ack {
+			return errors.New("Rabbit rechazó el mensaje")
+		}
+	case <-time.After(confirmTimeout):
+		// Una confirmación tardía desincronizaría las siguientes, se descarta el canal
+		r.reset()
+		return errors.New("tiempo de espera agotado esperando la confirmación de Rabbit")
+	}
+
 	log.Println("Mensaje publicado correctamente en RabbitMQ")
 	return nil
 }
+
+// Close cierra la conexión con RabbitMQ
+func (r *Rabbit) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset()
+}
@@ -3,6 +3,8 @@ package courses
 import (
 	"context"
 	"courses-api/domain/courses"
+	coursesServices "courses-api/services/courses"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,7 +14,7 @@ import (
 // Interface que define los métodos del servicio
 type Service interface {
 	CreateCourse(ctx context.Context, req courses.CreateCourseRequest) (courses.CourseResponse, error)
-	GetCourses(ctx context.Context) ([]courses.CourseResponse, error)
+	GetCourses(ctx context.Context, req courses.GetCoursesRequest) (courses.CoursesPageResponse, error)
 	GetCourseByID(ctx context.Context, id int64) (courses.CourseResponse, error)
 	UpdateCourse(ctx context.Context, id int64, req courses.UpdateCourseRequest) (courses.CourseResponse, error)
 	DeleteCourse(ctx context.Context, id int64) error
@@ -45,14 +47,53 @@ func (ctrl Controller) CreateCourse(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, course)
 }
 
-// Obtener todos los cursos
+// Obtener cursos paginados, filtrados y ordenados
 func (ctrl Controller) GetCourses(ctx *gin.Context) {
-	courses, err := ctrl.service.GetCourses(ctx.Request.Context())
+	var req courses.GetCoursesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Parámetros inválidos: " + err.Error()})
+		return
+	}
+
+	page, err := ctrl.service.GetCourses(ctx.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, coursesServices.ErrInvalidSort) || errors.Is(err, coursesServices.ErrInvalidAfterID) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Parámetros inválidos: " + err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error al listar cursos: " + err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, courses)
+
+	if req.AfterID > 0 {
+		// Con paginado por clave el total cuenta los cursos restantes desde after_id
+		if n := len(page.Results); n > 0 && int64(n) < page.Total {
+			page.Next = afterLink(ctx, page.Results[n-1].ID)
+		}
+		ctx.JSON(http.StatusOK, page)
+		return
+	}
+	if page.Page*page.PageSize < page.Total {
+		page.Next = pageLink(ctx, page.Page+1)
+	}
+	if page.Page > 1 {
+		page.Previous = pageLink(ctx, page.Page-1)
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// pageLink arma la URL de la página indicada conservando el resto de los parámetros
+func pageLink(ctx *gin.Context, page int64) string {
+	query := ctx.Request.URL.Query()
+	query.Set("page", strconv.FormatInt(page, 10))
+	return ctx.Request.URL.Path + "?" + query.Encode()
+}
+
+// afterLink arma la URL de la página siguiente a afterID en el paginado por clave
+func afterLink(ctx *gin.Context, afterID int64) string {
+	query := ctx.Request.URL.Query()
+	query.Set("after_id", strconv.FormatInt(afterID, 10))
+	return ctx.Request.URL.Path + "?" + query.Encode()
 }
 
 // Obtener curso por ID
@@ -63,6 +104,10 @@ func (ctrl Controller) GetCourseByID(ctx *gin.Context) {
 		return
 	}
 	course, err := ctrl.service.GetCourseByID(ctx.Request.Context(), courseID)
+	if errors.Is(err, coursesServices.ErrCourseNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Curso no encontrado"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener curso: " + err.Error()})
 		return
@@ -31,8 +31,29 @@ type CourseResponse struct {
 	ImageID      string  `json:"image_id"`
 	Capacity     int     `json:"capacity"`
 	Rating       float64 `json:"rating"`
+	CommentCount int     `json:"comment_count"`
+	CreatedAt    int64   `json:"created_at"`
+	Version      int64   `json:"version"`
 }
-type CursosNew struct {
-	Operation string `json:"operation"`
-	CourseID  int64  `json:"course_id"`
+
+// GetCoursesRequest parámetros de paginación, filtros y orden de GET /courses
+type GetCoursesRequest struct {
+	Page         int64   `form:"page"`
+	PageSize     int64   `form:"page_size"`
+	Category     string  `form:"category"`
+	InstructorID int64   `form:"instructor_id"`
+	MinRating    float64 `form:"rating"`
+	MinCapacity  int     `form:"capacity"`
+	Sort         string  `form:"sort"`     // name, rating o created_at; prefijo "-" para orden descendente
+	AfterID      int64   `form:"after_id"` // Paginado por clave: solo cursos con ID mayor, con el orden por ID
+}
+
+// CoursesPageResponse sobre de respuesta paginada de GET /courses
+type CoursesPageResponse struct {
+	Results  []CourseResponse `json:"results"`
+	Total    int64            `json:"total"`
+	Page     int64            `json:"page"`
+	PageSize int64            `json:"page_size"`
+	Next     string           `json:"next,omitempty"`
+	Previous string           `json:"previous,omitempty"`
 }
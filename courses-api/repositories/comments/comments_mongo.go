@@ -3,10 +3,9 @@ package repositories
 import (
 	"context"
 	"fmt"
-	"log"
-	"sync"
 
 	commentsDAO "courses-api/DAO/comments"
+	"courses-api/repositories/sequences"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,50 +16,50 @@ type CommentsMongo struct {
 	client     *mongo.Client
 	database   string
 	collection string
+	ids        sequences.Mongo
 }
 
-var (
-	commentCounter   int64
-	commentCounterMu sync.Mutex
-)
-
-func NewCommentsMongo(client *mongo.Client, database, collection string) *CommentsMongo {
+func NewCommentsMongo(client *mongo.Client, database, collection string, ids sequences.Mongo) *CommentsMongo {
 	return &CommentsMongo{
 		client:     client,
 		database:   database,
 		collection: collection,
+		ids:        ids,
 	}
 }
 
-// InitializeCommentCounter inicializa el contador de comentarios
-func InitializeCommentCounter(client *mongo.Client, dbName, collectionName string) {
-	collection := client.Database(dbName).Collection(collectionName)
-	var lastComment commentsDAO.Comment
+// Nombre de la secuencia de IDs de comentarios
+const commentsSequence = "comments"
 
-	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
-	err := collection.FindOne(context.Background(), bson.M{}, opts).Decode(&lastComment)
+// Initialize crea el índice único sobre id y sincroniza la secuencia con el mayor ID existente
+func (m *CommentsMongo) Initialize(ctx context.Context) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
-		log.Printf("No se encontró un comentario existente: %v. Iniciando el contador en 0.", err)
-		commentCounter = 0
-	} else {
-		commentCounter = lastComment.ID
-		log.Printf("Contador de comentarios inicializado en: %d", commentCounter)
+		return fmt.Errorf("failed to create comments id index: %v", err)
 	}
-}
 
-// getNextCommentID obtiene el siguiente ID de comentario de manera segura
-func getNextCommentID() int64 {
-	commentCounterMu.Lock()
-	defer commentCounterMu.Unlock()
-	commentCounter++
-	return commentCounter
+	var lastComment commentsDAO.Comment
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	err = collection.FindOne(ctx, bson.M{}, opts).Decode(&lastComment)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to find last comment: %v", err)
+	}
+	return m.ids.Seed(ctx, commentsSequence, lastComment.ID)
 }
 
 func (m *CommentsMongo) CreateComment(ctx context.Context, comment commentsDAO.Comment) (commentsDAO.Comment, error) {
-	comment.ID = getNextCommentID()
+	id, err := m.ids.NextID(ctx, commentsSequence)
+	if err != nil {
+		return commentsDAO.Comment{}, err
+	}
+	comment.ID = id
 
 	collection := m.client.Database(m.database).Collection(m.collection)
-	_, err := collection.InsertOne(ctx, comment)
+	_, err = collection.InsertOne(ctx, comment)
 	if err != nil {
 		return commentsDAO.Comment{}, fmt.Errorf("failed to insert comment: %v", err)
 	}
@@ -3,9 +3,13 @@ package repositories
 import (
 	"context"
 	coursesDAO "courses-api/DAO/courses"
+	outboxDAO "courses-api/DAO/outbox"
+	"courses-api/repositories/sequences"
+	"errors"
+	"events"
 	"fmt"
 	"log"
-	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,6 +24,7 @@ type MongoConfig struct {
 	Password   string
 	Database   string
 	Collection string
+	Outbox     string // Colección del outbox de eventos, en la misma base de datos
 }
 
 // Estructura del repositorio Mongo
@@ -27,6 +32,8 @@ type Mongo struct {
 	client     *mongo.Client
 	database   string
 	collection string
+	outbox     string
+	ids        sequences.Mongo
 }
 
 // Constante para la conexión
@@ -35,7 +42,7 @@ const (
 )
 
 // Nueva instancia de Mongo
-func NewMongo(config MongoConfig) Mongo {
+func NewMongo(config MongoConfig, ids sequences.Mongo) Mongo {
 	credentials := options.Credential{
 		Username: config.Username,
 		Password: config.Password,
@@ -54,70 +61,149 @@ func NewMongo(config MongoConfig) Mongo {
 		client:     client,
 		database:   config.Database,
 		collection: config.Collection,
+		outbox:     config.Outbox,
+		ids:        ids,
 	}
 }
 
-// Configuración del mutex y contador en memoria
-var (
-	counter   int64
-	counterMu sync.Mutex
-)
+// Nombre de la secuencia de IDs de cursos
+const coursesSequence = "courses"
 
-// Inicializa el contador en función del último ID en la colección
-func InitializeCounter(mongoClient *mongo.Client, dbName, collectionName string) {
-	collection := mongoClient.Database(dbName).Collection(collectionName)
-	var lastCourse coursesDAO.Course
+// Initialize crea el índice único sobre id y sincroniza la secuencia con el mayor ID existente
+func (m Mongo) Initialize(ctx context.Context) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create courses id index: %v", err)
+	}
 
 	// Buscar el curso con el ID más alto
+	var lastCourse coursesDAO.Course
 	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
-	err := collection.FindOne(context.Background(), bson.M{}, opts).Decode(&lastCourse)
-	if err != nil {
-		log.Printf("No se encontró un curso existente: %v. Iniciando el contador en 0.", err)
-		counter = 0
-	} else {
-		counter = lastCourse.ID
-		log.Printf("Contador inicializado en: %d", counter)
+	err = collection.FindOne(ctx, bson.M{}, opts).Decode(&lastCourse)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to find last course: %v", err)
 	}
+	return m.ids.Seed(ctx, coursesSequence, lastCourse.ID)
 }
 
-// Obtener el próximo ID de manera segura
-func getNextID() int64 {
-	counterMu.Lock()
-	defer counterMu.Unlock()
-	counter++
-	return counter
+// withOutbox ejecuta write y registra el evento en el outbox dentro de la misma
+// transacción, de modo que el cambio y su evento se confirman o descartan juntos.
+// Salvo en DELETE, el evento lleva el curso tal como quedó tras el cambio, leído
+// dentro de la transacción, para que los consumidores no tengan que consultarlo.
+// Devuelve ese mismo curso, con la versión que le asignó la escritura.
+func (m Mongo) withOutbox(ctx context.Context, operation string, courseID int64, write func(sc mongo.SessionContext) error) (coursesDAO.Course, error) {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return coursesDAO.Course{}, fmt.Errorf("failed to start session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	var written coursesDAO.Course
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := write(sc); err != nil {
+			return nil, err
+		}
+		event := outboxDAO.Event{
+			Operation: operation,
+			CourseID:  courseID,
+			Status:    outboxDAO.StatusPending,
+			CreatedAt: time.Now().Unix(),
+		}
+		if operation != string(events.OperationDelete) {
+			var course coursesDAO.Course
+			if err := m.client.Database(m.database).Collection(m.collection).FindOne(sc, bson.M{"id": courseID}).Decode(&course); err != nil {
+				return nil, fmt.Errorf("failed to read course for outbox event: %v", err)
+			}
+			event.Course = &course
+			written = course
+		}
+		if _, err := m.client.Database(m.database).Collection(m.outbox).InsertOne(sc, event); err != nil {
+			return nil, fmt.Errorf("failed to insert outbox event: %v", err)
+		}
+		return nil, nil
+	})
+	return written, err
 }
 
-// Crear curso con el ID generado en memoria
+// Crear curso con el ID asignado por la secuencia compartida
 func (m Mongo) CreateCourse(ctx context.Context, course coursesDAO.Course) (coursesDAO.Course, error) {
-	course.ID = getNextID()
+	id, err := m.ids.NextID(ctx, coursesSequence)
+	if err != nil {
+		return coursesDAO.Course{}, err
+	}
+	course.ID = id
 	course.Rating = 0 // Inicializar el rating en 0
+	course.Version = 1
 
 	collection := m.client.Database(m.database).Collection(m.collection)
-	_, err := collection.InsertOne(ctx, course)
+	_, err = m.withOutbox(ctx, string(events.OperationCreate), course.ID, func(sc mongo.SessionContext) error {
+		if _, err := collection.InsertOne(sc, course); err != nil {
+			return fmt.Errorf("failed to insert course: %v", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return coursesDAO.Course{}, fmt.Errorf("failed to insert course: %v", err)
+		return coursesDAO.Course{}, err
 	}
 	return course, nil
 }
 
-func (m Mongo) GetCourses(ctx context.Context) ([]coursesDAO.Course, error) {
-	var courses []coursesDAO.Course
+func (m Mongo) GetCourses(ctx context.Context, filter coursesDAO.CoursesFilter) ([]coursesDAO.Course, int64, error) {
 	collection := m.client.Database(m.database).Collection(m.collection)
-	cursor, err := collection.Find(ctx, bson.M{})
+
+	query := bson.M{}
+	if filter.Category != "" {
+		query["category"] = filter.Category
+	}
+	if filter.InstructorID != 0 {
+		query["instructor_id"] = filter.InstructorID
+	}
+	if filter.MinRating > 0 {
+		query["rating"] = bson.M{"$gte": filter.MinRating}
+	}
+	if filter.MinCapacity > 0 {
+		query["capacity"] = bson.M{"$gte": filter.MinCapacity}
+	}
+	if filter.AfterID > 0 {
+		query["id"] = bson.M{"$gt": filter.AfterID}
+	}
+
+	total, err := collection.CountDocuments(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find courses: %v", err)
+		return nil, 0, fmt.Errorf("failed to count courses: %v", err)
 	}
+
+	// Se agrega el id como desempate para que el orden entre páginas sea estable
+	sort := bson.D{{Key: "id", Value: 1}}
+	if filter.SortField != "" {
+		sort = bson.D{{Key: filter.SortField, Value: filter.SortOrder}, {Key: "id", Value: 1}}
+	}
+	opts := options.Find().SetSort(sort).SetSkip(filter.Skip).SetLimit(filter.Limit)
+
+	cursor, err := collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find courses: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var courses []coursesDAO.Course
 	if err := cursor.All(ctx, &courses); err != nil {
-		return nil, fmt.Errorf("failed to decode courses: %v", err)
+		return nil, 0, fmt.Errorf("failed to decode courses: %v", err)
 	}
-	return courses, nil
+	return courses, total, nil
 }
 
 func (m Mongo) GetCourseByID(ctx context.Context, id int64) (coursesDAO.Course, error) {
 	var course coursesDAO.Course
 	collection := m.client.Database(m.database).Collection(m.collection)
 	err := collection.FindOne(ctx, bson.M{"id": id}).Decode(&course)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return coursesDAO.Course{}, coursesDAO.ErrNotFound
+	}
 	if err != nil {
 		return coursesDAO.Course{}, fmt.Errorf("failed to find course: %v", err)
 	}
@@ -127,30 +213,36 @@ func (m Mongo) GetCourseByID(ctx context.Context, id int64) (coursesDAO.Course,
 func (m Mongo) UpdateCourse(ctx context.Context, course coursesDAO.Course) (coursesDAO.Course, error) {
 	collection := m.client.Database(m.database).Collection(m.collection)
 	filter := bson.M{"id": course.ID}
-	update := bson.M{"$set": course}
-	_, err := collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return coursesDAO.Course{}, fmt.Errorf("failed to update course: %v", err)
-	}
-	return course, nil
+	course.Version = 0 // La versión solo avanza con $inc
+	update := bson.M{"$set": course, "$inc": bson.M{"version": 1}}
+	return m.withOutbox(ctx, string(events.OperationUpdate), course.ID, func(sc mongo.SessionContext) error {
+		if _, err := collection.UpdateOne(sc, filter, update); err != nil {
+			return fmt.Errorf("failed to update course: %v", err)
+		}
+		return nil
+	})
 }
 
 func (m Mongo) DeleteCourse(ctx context.Context, id int64) error {
 	collection := m.client.Database(m.database).Collection(m.collection)
-	_, err := collection.DeleteOne(ctx, bson.M{"id": id})
-	if err != nil {
-		return fmt.Errorf("failed to delete course: %v", err)
-	}
-	return nil
+	_, err := m.withOutbox(ctx, string(events.OperationDelete), id, func(sc mongo.SessionContext) error {
+		if _, err := collection.DeleteOne(sc, bson.M{"id": id}); err != nil {
+			return fmt.Errorf("failed to delete course: %v", err)
+		}
+		return nil
+	})
+	return err
 }
 
 func (m Mongo) UpdateCourseRating(ctx context.Context, courseID int64, newRating float64) error {
 	collection := m.client.Database(m.database).Collection(m.collection)
 	filter := bson.M{"id": courseID}
-	update := bson.M{"$set": bson.M{"rating": newRating}}
-	_, err := collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to update course rating: %v", err)
-	}
-	return nil
+	update := bson.M{"$set": bson.M{"rating": newRating}, "$inc": bson.M{"version": 1}}
+	_, err := m.withOutbox(ctx, string(events.OperationUpdate), courseID, func(sc mongo.SessionContext) error {
+		if _, err := collection.UpdateOne(sc, filter, update); err != nil {
+			return fmt.Errorf("failed to update course rating: %v", err)
+		}
+		return nil
+	})
+	return err
 }
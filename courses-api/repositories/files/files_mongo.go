@@ -3,68 +3,66 @@ package files
 import (
 	"context"
 	"fmt"
-	"log"
-	"sync"
 
 	filesDAO "courses-api/DAO/files"
+	"courses-api/repositories/sequences"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Configuración del mutex y contador en memoria
-var (
-	fileCounter   int64
-	fileCounterMu sync.Mutex
-)
-
 // Repositorio MongoDB para archivos
 type Mongo struct {
 	client     *mongo.Client
 	database   string
 	collection string
+	ids        sequences.Mongo
 }
 
 // Constructor del repositorio Mongo
-func NewMongo(client *mongo.Client, db, collection string) Mongo {
+func NewMongo(client *mongo.Client, db, collection string, ids sequences.Mongo) Mongo {
 	return Mongo{
 		client:     client,
 		database:   db,
 		collection: collection,
+		ids:        ids,
 	}
 }
 
-// Inicializa el contador en función del último ID en la colección
-func InitializeFileCounter(mongoClient *mongo.Client, dbName, collectionName string) {
-	collection := mongoClient.Database(dbName).Collection(collectionName)
-	var lastFile filesDAO.File
+// Nombre de la secuencia de IDs de archivos
+const filesSequence = "files"
 
-	// Buscar el archivo con el ID más alto
-	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
-	err := collection.FindOne(context.Background(), bson.M{}, opts).Decode(&lastFile)
+// Initialize crea el índice único sobre id y sincroniza la secuencia con el mayor ID existente
+func (m Mongo) Initialize(ctx context.Context) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
-		log.Printf("No se encontró un archivo existente: %v. Iniciando el contador en 0.", err)
-		fileCounter = 0
-	} else {
-		fileCounter = lastFile.ID
-		log.Printf("Contador de archivos inicializado en: %d", fileCounter)
+		return fmt.Errorf("failed to create files id index: %v", err)
 	}
-}
 
-// Obtener el próximo ID de manera segura
-func getNextFileID() int64 {
-	fileCounterMu.Lock()
-	defer fileCounterMu.Unlock()
-	fileCounter++
-	return fileCounter
+	// Buscar el archivo con el ID más alto
+	var lastFile filesDAO.File
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	err = collection.FindOne(ctx, bson.M{}, opts).Decode(&lastFile)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to find last file: %v", err)
+	}
+	return m.ids.Seed(ctx, filesSequence, lastFile.ID)
 }
 
 // Crear archivo
 func (m Mongo) CreateFile(ctx context.Context, file filesDAO.File) (filesDAO.File, error) {
-	file.ID = getNextFileID()
+	id, err := m.ids.NextID(ctx, filesSequence)
+	if err != nil {
+		return filesDAO.File{}, err
+	}
+	file.ID = id
 
-	_, err := m.client.Database(m.database).Collection(m.collection).InsertOne(ctx, file)
+	_, err = m.client.Database(m.database).Collection(m.collection).InsertOne(ctx, file)
 	if err != nil {
 		return filesDAO.File{}, fmt.Errorf("failed to insert file: %v", err)
 	}
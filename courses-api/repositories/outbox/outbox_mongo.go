@@ -0,0 +1,122 @@
+package outbox
+
+import (
+	"context"
+	outboxDAO "courses-api/DAO/outbox"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repositorio MongoDB del outbox de eventos de cursos
+type Mongo struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// Constructor del repositorio del outbox
+func NewMongo(client *mongo.Client, database, collection string) Mongo {
+	return Mongo{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// Initialize crea los índices usados para buscar los eventos pendientes en orden
+// y para eliminar los ya publicados
+func (m Mongo) Initialize(ctx context.Context) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "sent_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %v", err)
+	}
+	return nil
+}
+
+// ClaimNext reserva durante lease el evento pendiente más antiguo que no esté
+// reservado por otra instancia. Devuelve nil si no hay eventos para publicar.
+func (m Mongo) ClaimNext(ctx context.Context, lease time.Duration) (*outboxDAO.Event, error) {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	now := time.Now()
+	filter := bson.M{
+		"status": outboxDAO.StatusPending,
+		"$or": bson.A{
+			bson.M{"claimed_until": bson.M{"$exists": false}},
+			bson.M{"claimed_until": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"claimed_until": now.Add(lease).Unix()}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var event outboxDAO.Event
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending event: %v", err)
+	}
+	return &event, nil
+}
+
+// MarkSent marca el evento como publicado
+func (m Mongo) MarkSent(ctx context.Context, event outboxDAO.Event) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	update := bson.M{
+		"$set":   bson.M{"status": outboxDAO.StatusSent, "sent_at": time.Now().Unix()},
+		"$unset": bson.M{"claimed_until": ""},
+		"$inc":   bson.M{"attempts": 1},
+	}
+	if _, err := collection.UpdateByID(ctx, event.ID, update); err != nil {
+		return fmt.Errorf("failed to mark event as sent: %v", err)
+	}
+	return nil
+}
+
+// MarkFailed registra un intento fallido de publicación. El evento sigue
+// pendiente, o pasa a fallido si dead indica que agotó sus intentos.
+func (m Mongo) MarkFailed(ctx context.Context, event outboxDAO.Event, cause error, dead bool) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	set := bson.M{"last_error": cause.Error()}
+	if dead {
+		set["status"] = outboxDAO.StatusFailed
+	}
+	update := bson.M{
+		"$set":   set,
+		"$unset": bson.M{"claimed_until": ""},
+		"$inc":   bson.M{"attempts": 1},
+	}
+	if _, err := collection.UpdateByID(ctx, event.ID, update); err != nil {
+		return fmt.Errorf("failed to record event failure: %v", err)
+	}
+	return nil
+}
+
+// Attempts devuelve los intentos de publicación fallidos del evento
+func (m Mongo) Attempts(event outboxDAO.Event) int {
+	return event.Attempts
+}
+
+// DeleteSent elimina los eventos publicados antes de before
+func (m Mongo) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	result, err := collection.DeleteMany(ctx, bson.M{
+		"status":  outboxDAO.StatusSent,
+		"sent_at": bson.M{"$lt": before.Unix()},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent events: %v", err)
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,65 @@
+package sequences
+
+import (
+	"context"
+	sequencesDAO "courses-api/DAO/sequences"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo asigna IDs numéricos a partir de una colección de contadores compartida,
+// de modo que varias réplicas de la API nunca entreguen el mismo ID
+type Mongo struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// Constructor del repositorio de secuencias
+func NewMongo(client *mongo.Client, database, collection string) Mongo {
+	return Mongo{
+		client:     client,
+		database:   database,
+		collection: collection,
+	}
+}
+
+// Seed asegura que la secuencia no quede por debajo de lastID (el mayor ID ya existente).
+// Es idempotente y se puede ejecutar desde todas las réplicas al iniciar.
+func (m Mongo) Seed(ctx context.Context, name string, lastID int64) error {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	filter := bson.M{"_id": name}
+	update := bson.M{"$max": bson.M{"seq": lastID}}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := collection.UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		// Otra réplica creó el contador al mismo tiempo, reintentamos sobre el documento existente
+		_, err = collection.UpdateOne(ctx, filter, update, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seed sequence %s: %v", name, err)
+	}
+	return nil
+}
+
+// NextID incrementa atómicamente la secuencia y devuelve el nuevo valor
+func (m Mongo) NextID(ctx context.Context, name string) (int64, error) {
+	collection := m.client.Database(m.database).Collection(m.collection)
+	filter := bson.M{"_id": name}
+	update := bson.M{"$inc": bson.M{"seq": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter sequencesDAO.Counter
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next id for %s: %v", name, err)
+	}
+	return counter.Seq, nil
+}
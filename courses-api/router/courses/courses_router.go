@@ -18,7 +18,7 @@ func SetupRouter(courseController courses.Controller, commentController comments
 	coursesGroup := r.Group("/courses")
 	{
 		coursesGroup.POST("", courseController.CreateCourse)       // Crear curso
-		coursesGroup.GET("", courseController.GetCourses)          // Obtener todos los cursos
+		coursesGroup.GET("", courseController.GetCourses)          // Listar cursos paginados y filtrados
 		coursesGroup.GET("/:id", courseController.GetCourseByID)   // Obtener curso por ID
 		coursesGroup.PUT("/:id", courseController.UpdateCourse)    // Actualizar curso
 		coursesGroup.DELETE("/:id", courseController.DeleteCourse) // Eliminar curso
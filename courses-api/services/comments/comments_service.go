@@ -96,6 +96,7 @@ func (s Service) GetCommentsByCourseID(ctx context.Context, courseID int64) ([]c
 	return commentsResponse, nil
 }
 
+// updateCourseRating recalcula el rating promedio y la cantidad de comentarios del curso
 func (s Service) updateCourseRating(ctx context.Context, courseID int64) error {
 	comments, err := s.commentsRepository.GetCommentsByCourseID(ctx, courseID)
 	if err != nil {
@@ -114,6 +115,7 @@ func (s Service) updateCourseRating(ctx context.Context, courseID int64) error {
 	}
 
 	course.Rating = newAverageRating
+	course.CommentCount = len(comments)
 
 	_, err = s.coursesRepository.UpdateCourse(ctx, course)
 	if err != nil {
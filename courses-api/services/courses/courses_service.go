@@ -7,12 +7,36 @@ import (
 	"courses-api/domain/courses"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
+// Valores por defecto y máximos de la paginación de cursos
+const (
+	DefaultPageSize int64 = 20
+	MaxPageSize     int64 = 100
+)
+
+// ErrInvalidSort se devuelve cuando el parámetro sort no corresponde a un campo ordenable
+var ErrInvalidSort = errors.New("sort must be one of name, rating or created_at, optionally prefixed with '-'")
+
+// ErrInvalidAfterID se devuelve cuando after_id se combina con otro orden que no sea por ID
+var ErrInvalidAfterID = errors.New("after_id can only be used with the default sort by id")
+
+// ErrCourseNotFound se devuelve cuando el curso no existe
+var ErrCourseNotFound = errors.New("course not found")
+
+// sortableFields campos por los que se puede ordenar el listado de cursos
+var sortableFields = map[string]bool{
+	"name":       true,
+	"rating":     true,
+	"created_at": true,
+}
+
 // Repository interface para las operaciones de curso
 type Repository interface {
 	CreateCourse(ctx context.Context, course coursesDAO.Course) (coursesDAO.Course, error)
-	GetCourses(ctx context.Context) ([]coursesDAO.Course, error)
+	GetCourses(ctx context.Context, filter coursesDAO.CoursesFilter) ([]coursesDAO.Course, int64, error)
 	GetCourseByID(ctx context.Context, id int64) (coursesDAO.Course, error)
 	UpdateCourse(ctx context.Context, course coursesDAO.Course) (coursesDAO.Course, error)
 	DeleteCourse(ctx context.Context, id int64) error
@@ -23,10 +47,6 @@ type CommentsRepository interface {
 	DeleteCommentsByCourseID(ctx context.Context, courseID int64) error
 }
 
-type Queue interface {
-	Publish(courseNew courses.CursosNew) error
-}
-
 // FilesRepository interface para las operaciones de archivos
 type FilesRepository interface {
 	DeleteFilesByCourseID(ctx context.Context, courseID int64) error
@@ -37,17 +57,15 @@ type Service struct {
 	repository         Repository
 	commentsRepository CommentsRepository
 	filesRepository    FilesRepository
-	eventsQueue        Queue
 	httpClient         *clients.HTTPClient
 }
 
 // NewService constructor para el servicio de cursos
-func NewService(repository Repository, commentsRepository CommentsRepository, filesRepository FilesRepository, eventsQueue Queue, httpClient *clients.HTTPClient) Service {
+func NewService(repository Repository, commentsRepository CommentsRepository, filesRepository FilesRepository, httpClient *clients.HTTPClient) Service {
 	return Service{
 		repository:         repository,
 		commentsRepository: commentsRepository,
 		filesRepository:    filesRepository,
-		eventsQueue:        eventsQueue,
 		httpClient:         httpClient,
 	}
 }
@@ -62,6 +80,7 @@ func (s Service) CreateCourse(ctx context.Context, req courses.CreateCourseReque
 		ImageID:      req.ImageID,
 		Capacity:     req.Capacity,
 		Rating:       0, // Inicialmente, el rating es 0
+		CreatedAt:    time.Now().Unix(),
 	}
 
 	createdCourse, err := s.repository.CreateCourse(ctx, course)
@@ -69,15 +88,6 @@ func (s Service) CreateCourse(ctx context.Context, req courses.CreateCourseReque
 		return courses.CourseResponse{}, fmt.Errorf("failed to create course: %v", err)
 	}
 
-	go func() {
-		if err := s.eventsQueue.Publish(courses.CursosNew{
-			Operation: "POST",
-			CourseID:  createdCourse.ID,
-		}); err != nil {
-			fmt.Println(fmt.Sprintf("Error al publicar nuevo curso: %v", err))
-		}
-	}()
-
 	return courses.CourseResponse{
 		ID:           createdCourse.ID,
 		Name:         createdCourse.Name,
@@ -88,16 +98,53 @@ func (s Service) CreateCourse(ctx context.Context, req courses.CreateCourseReque
 		ImageID:      createdCourse.ImageID,
 		Capacity:     createdCourse.Capacity,
 		Rating:       createdCourse.Rating,
+		CommentCount: createdCourse.CommentCount,
+		CreatedAt:    createdCourse.CreatedAt,
+		Version:      createdCourse.Version,
 	}, nil
 }
 
-func (s Service) GetCourses(ctx context.Context) ([]courses.CourseResponse, error) {
-	coursesDAO, err := s.repository.GetCourses(ctx)
+func (s Service) GetCourses(ctx context.Context, req courses.GetCoursesRequest) (courses.CoursesPageResponse, error) {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 {
+		req.PageSize = DefaultPageSize
+	}
+	if req.PageSize > MaxPageSize {
+		req.PageSize = MaxPageSize
+	}
+
+	filter := coursesDAO.CoursesFilter{
+		Category:     req.Category,
+		InstructorID: req.InstructorID,
+		MinRating:    req.MinRating,
+		MinCapacity:  req.MinCapacity,
+		Skip:         (req.Page - 1) * req.PageSize,
+		Limit:        req.PageSize,
+		AfterID:      req.AfterID,
+	}
+	if req.AfterID > 0 && req.Sort != "" {
+		return courses.CoursesPageResponse{}, ErrInvalidAfterID
+	}
+	if req.Sort != "" {
+		field := strings.TrimPrefix(req.Sort, "-")
+		if !sortableFields[field] {
+			return courses.CoursesPageResponse{}, ErrInvalidSort
+		}
+		filter.SortField = field
+		filter.SortOrder = 1
+		if strings.HasPrefix(req.Sort, "-") {
+			filter.SortOrder = -1
+		}
+	}
+
+	coursesDAO, total, err := s.repository.GetCourses(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get courses: %v", err)
+		return courses.CoursesPageResponse{}, fmt.Errorf("failed to get courses: %v", err)
 	}
 
-	var coursesResponse []courses.CourseResponse
+	coursesResponse := make([]courses.CourseResponse, 0, len(coursesDAO))
 	for _, course := range coursesDAO {
 		coursesResponse = append(coursesResponse, courses.CourseResponse{
 			ID:           course.ID,
@@ -109,14 +156,25 @@ func (s Service) GetCourses(ctx context.Context) ([]courses.CourseResponse, erro
 			ImageID:      course.ImageID,
 			Capacity:     course.Capacity,
 			Rating:       course.Rating,
+			CommentCount: course.CommentCount,
+			CreatedAt:    course.CreatedAt,
+			Version:      course.Version,
 		})
 	}
 
-	return coursesResponse, nil
+	return courses.CoursesPageResponse{
+		Results:  coursesResponse,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
 }
 
 func (s Service) GetCourseByID(ctx context.Context, id int64) (courses.CourseResponse, error) {
 	course, err := s.repository.GetCourseByID(ctx, id)
+	if errors.Is(err, coursesDAO.ErrNotFound) {
+		return courses.CourseResponse{}, ErrCourseNotFound
+	}
 	if err != nil {
 		return courses.CourseResponse{}, fmt.Errorf("failed to get course: %v", err)
 	}
@@ -131,6 +189,9 @@ func (s Service) GetCourseByID(ctx context.Context, id int64) (courses.CourseRes
 		ImageID:      course.ImageID,
 		Capacity:     course.Capacity,
 		Rating:       course.Rating,
+		CommentCount: course.CommentCount,
+		CreatedAt:    course.CreatedAt,
+		Version:      course.Version,
 	}, nil
 }
 
@@ -168,15 +229,6 @@ func (s Service) UpdateCourse(ctx context.Context, id int64, req courses.UpdateC
 		return courses.CourseResponse{}, fmt.Errorf("failed to update course: %v", err)
 	}
 
-	go func() {
-		if err := s.eventsQueue.Publish(courses.CursosNew{
-			Operation: "PUT",
-			CourseID:  updatedCourse.ID,
-		}); err != nil {
-			fmt.Println(fmt.Sprintf("Error al publicar actualización de curso: %v", err))
-		}
-	}()
-
 	return courses.CourseResponse{
 		ID:           updatedCourse.ID,
 		Name:         updatedCourse.Name,
@@ -187,6 +239,9 @@ func (s Service) UpdateCourse(ctx context.Context, id int64, req courses.UpdateC
 		ImageID:      updatedCourse.ImageID,
 		Capacity:     updatedCourse.Capacity,
 		Rating:       updatedCourse.Rating,
+		CommentCount: updatedCourse.CommentCount,
+		CreatedAt:    updatedCourse.CreatedAt,
+		Version:      updatedCourse.Version,
 	}, nil
 }
 
@@ -213,22 +268,12 @@ func (s Service) DeleteCourse(ctx context.Context, id int64) error {
 		return fmt.Errorf("error al eliminar los archivos del curso: %v", err)
 	}
 
-	// Eliminar el curso del repositorio
-	err = s.repository.DeleteCourse(ctx, id) // Asegúrate de que DeleteCourse acepte int64 en el repositorio
+	// Eliminar el curso del repositorio, el evento se registra en el outbox en la misma transacción
+	err = s.repository.DeleteCourse(ctx, id)
 	if err != nil {
 		return fmt.Errorf("error al eliminar el curso: %v", err)
 	}
 
-	// Publicar el evento en la cola de RabbitMQ
-	go func() {
-		if err := s.eventsQueue.Publish(courses.CursosNew{
-			Operation: "DELETE",
-			CourseID:  id,
-		}); err != nil {
-			fmt.Println(fmt.Sprintf("Error al publicar eliminación de curso: %v", err))
-		}
-	}()
-
 	return nil
 }
 
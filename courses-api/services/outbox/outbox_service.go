@@ -0,0 +1,62 @@
+package outbox
+
+import (
+	coursesDAO "courses-api/DAO/courses"
+	outboxDAO "courses-api/DAO/outbox"
+	"events"
+	"events/outbox"
+	"fmt"
+	"time"
+)
+
+type Queue interface {
+	Publish(event events.CourseEvent) error
+}
+
+// NewRelay relay que publica en RabbitMQ los eventos de cursos registrados en el outbox
+func NewRelay(repository outbox.Store[outboxDAO.Event], queue Queue, config outbox.Config) outbox.Relay[outboxDAO.Event] {
+	return outbox.NewRelay(repository, func(event outboxDAO.Event) error {
+		published := courseEvent(event)
+		if err := published.Validate(); err != nil {
+			return fmt.Errorf("%w: evento %s inválido: %v", outbox.ErrRejected, event.ID.Hex(), err)
+		}
+		if err := queue.Publish(published); err != nil {
+			return fmt.Errorf("error al publicar el evento %s: %w", event.ID.Hex(), err)
+		}
+		return nil
+	}, config)
+}
+
+// courseEvent convierte el evento guardado en el outbox al contrato de eventos.
+// El ID del evento en el outbox se mantiene entre reintentos.
+func courseEvent(event outboxDAO.Event) events.CourseEvent {
+	return events.CourseEvent{
+		EventID:       event.ID.Hex(),
+		SchemaVersion: events.SchemaVersion,
+		Operation:     events.Operation(event.Operation),
+		CourseID:      event.CourseID,
+		Timestamp:     time.Unix(event.CreatedAt, 0).UTC(),
+		Course:        snapshot(event.Course),
+	}
+}
+
+// snapshot convierte el curso guardado en el outbox al snapshot del contrato de eventos
+func snapshot(course *coursesDAO.Course) *events.CourseSnapshot {
+	if course == nil {
+		return nil
+	}
+	return &events.CourseSnapshot{
+		ID:           course.ID,
+		Name:         course.Name,
+		Description:  course.Description,
+		Category:     course.Category,
+		Duration:     course.Duration,
+		InstructorID: course.InstructorID,
+		ImageID:      course.ImageID,
+		Capacity:     course.Capacity,
+		Rating:       course.Rating,
+		CommentCount: course.CommentCount,
+		CreatedAt:    course.CreatedAt,
+		Version:      course.Version,
+	}
+}
@@ -0,0 +1,129 @@
+// Package events define el contrato de los mensajes que los servicios intercambian
+// por RabbitMQ: los de cursos, que courses-api publica y search-api e
+// inscriptions-api consumen, y los de inscripciones, que publica inscriptions-api.
+// Todos los servicios dependen de este módulo, por lo que cualquier cambio en el
+// esquema impacta a todos a la vez.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion versión actual del esquema de CourseEvent. Debe incrementarse
+// ante cualquier cambio incompatible en la estructura del mensaje.
+const SchemaVersion = 1
+
+// Operation tipo de cambio que describe el evento
+type Operation string
+
+const (
+	OperationCreate Operation = "CREATE"
+	OperationUpdate Operation = "UPDATE"
+	OperationDelete Operation = "DELETE"
+)
+
+// Operaciones usadas por los mensajes anteriores al esquema versionado
+var legacyOperations = map[string]Operation{
+	"POST":   OperationCreate,
+	"PUT":    OperationUpdate,
+	"DELETE": OperationDelete,
+}
+
+// CourseSnapshot estado completo del curso al momento del evento
+type CourseSnapshot struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	Category     string  `json:"category"`
+	Duration     string  `json:"duration"`
+	InstructorID int64   `json:"instructor_id"`
+	ImageID      string  `json:"image_id"`
+	Capacity     int     `json:"capacity"`
+	Rating       float64 `json:"rating"`
+	CommentCount int     `json:"comment_count"`
+	CreatedAt    int64   `json:"created_at"`
+	// Version crece con cada escritura del curso; permite descartar snapshots
+	// que llegan después de uno más nuevo. Es 0 en cursos anteriores al campo.
+	Version int64 `json:"version,omitempty"`
+}
+
+// CourseEvent mensaje publicado en courses_queue ante cada cambio de un curso
+type CourseEvent struct {
+	EventID       string          `json:"event_id"`
+	SchemaVersion int             `json:"schema_version"`
+	Operation     Operation       `json:"operation"`
+	CourseID      int64           `json:"course_id"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Course        *CourseSnapshot `json:"course,omitempty"` // Estado tras el cambio; nunca en DELETE ni en mensajes anteriores al snapshot
+}
+
+// Errores de validación del contrato
+var (
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	ErrUnknownOperation   = errors.New("unknown operation")
+	ErrMissingCourseID    = errors.New("missing course id")
+	ErrMissingEventID     = errors.New("missing event id")
+	ErrSnapshotMismatch   = errors.New("course snapshot does not match course id")
+)
+
+// Validate verifica que el evento cumpla con el contrato de la versión actual
+func (e CourseEvent) Validate() error {
+	if e.SchemaVersion < 1 || e.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.SchemaVersion)
+	}
+	if e.EventID == "" {
+		return ErrMissingEventID
+	}
+	switch e.Operation {
+	case OperationCreate, OperationUpdate, OperationDelete:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownOperation, e.Operation)
+	}
+	if e.CourseID <= 0 {
+		return ErrMissingCourseID
+	}
+	if e.Course != nil {
+		if e.Operation == OperationDelete || e.Course.ID != e.CourseID {
+			return ErrSnapshotMismatch
+		}
+	}
+	return nil
+}
+
+// Encode valida y serializa el evento
+func Encode(event CourseEvent) ([]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid course event: %w", err)
+	}
+	return json.Marshal(event)
+}
+
+// Decode deserializa y valida un mensaje. Los mensajes sin schema_version
+// (anteriores al contrato, con operaciones POST/PUT/DELETE) se normalizan a la
+// versión actual para que puedan seguir procesándose.
+func Decode(body []byte) (CourseEvent, error) {
+	var event CourseEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return CourseEvent{}, fmt.Errorf("invalid course event payload: %w", err)
+	}
+
+	if event.SchemaVersion == 0 {
+		operation, ok := legacyOperations[string(event.Operation)]
+		if !ok {
+			return CourseEvent{}, fmt.Errorf("invalid course event: %w: %q", ErrUnknownOperation, event.Operation)
+		}
+		event.Operation = operation
+		event.SchemaVersion = SchemaVersion
+		if event.EventID == "" {
+			event.EventID = fmt.Sprintf("legacy-%s-%d", operation, event.CourseID)
+		}
+	}
+
+	if err := event.Validate(); err != nil {
+		return CourseEvent{}, fmt.Errorf("invalid course event: %w", err)
+	}
+	return event, nil
+}
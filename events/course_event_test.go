@@ -0,0 +1,182 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validEvent() CourseEvent {
+	return CourseEvent{
+		EventID:       "6650f1a2b3c4d5e6f7a8b9c0",
+		SchemaVersion: SchemaVersion,
+		Operation:     OperationUpdate,
+		CourseID:      7,
+		Timestamp:     time.Date(2024, 5, 24, 13, 0, 0, 0, time.UTC),
+		Course: &CourseSnapshot{
+			ID:           7,
+			Name:         "Go avanzado",
+			Description:  "Concurrencia y generics",
+			Category:     "programacion",
+			Duration:     "8 semanas",
+			InstructorID: 3,
+			ImageID:      "img-7",
+			Capacity:     30,
+			Rating:       4.5,
+			CommentCount: 12,
+			CreatedAt:    1716555600,
+			Version:      4,
+		},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	deleted := validEvent()
+	deleted.Operation = OperationDelete
+	deleted.Course = nil
+
+	tests := []struct {
+		name  string
+		event CourseEvent
+	}{
+		{"update con snapshot", validEvent()},
+		{"delete sin snapshot", deleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := Encode(tt.event)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			got, err := Decode(body)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.event) {
+				t.Errorf("Decode(Encode(e)) = %+v, want %+v", got, tt.event)
+			}
+		})
+	}
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want CourseEvent
+	}{
+		{
+			name: "POST",
+			body: `{"operation":"POST","course_id":4}`,
+			want: CourseEvent{EventID: "legacy-CREATE-4", SchemaVersion: SchemaVersion, Operation: OperationCreate, CourseID: 4},
+		},
+		{
+			name: "PUT",
+			body: `{"operation":"PUT","course_id":5}`,
+			want: CourseEvent{EventID: "legacy-UPDATE-5", SchemaVersion: SchemaVersion, Operation: OperationUpdate, CourseID: 5},
+		},
+		{
+			name: "DELETE",
+			body: `{"operation":"DELETE","course_id":6}`,
+			want: CourseEvent{EventID: "legacy-DELETE-6", SchemaVersion: SchemaVersion, Operation: OperationDelete, CourseID: 6},
+		},
+		{
+			name: "conserva el event_id",
+			body: `{"event_id":"abc","operation":"PUT","course_id":5}`,
+			want: CourseEvent{EventID: "abc", SchemaVersion: SchemaVersion, Operation: OperationUpdate, CourseID: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{
+			name: "operación desconocida",
+			body: `{"event_id":"a","schema_version":1,"operation":"PATCH","course_id":1}`,
+			want: ErrUnknownOperation,
+		},
+		{
+			name: "operación legacy desconocida",
+			body: `{"operation":"PATCH","course_id":1}`,
+			want: ErrUnknownOperation,
+		},
+		{
+			name: "versión futura",
+			body: `{"event_id":"a","schema_version":2,"operation":"UPDATE","course_id":1}`,
+			want: ErrUnsupportedVersion,
+		},
+		{
+			name: "versión negativa",
+			body: `{"event_id":"a","schema_version":-1,"operation":"UPDATE","course_id":1}`,
+			want: ErrUnsupportedVersion,
+		},
+		{
+			name: "sin event_id",
+			body: `{"schema_version":1,"operation":"UPDATE","course_id":1}`,
+			want: ErrMissingEventID,
+		},
+		{
+			name: "sin course_id",
+			body: `{"event_id":"a","schema_version":1,"operation":"UPDATE"}`,
+			want: ErrMissingCourseID,
+		},
+		{
+			name: "snapshot en DELETE",
+			body: `{"event_id":"a","schema_version":1,"operation":"DELETE","course_id":1,"course":{"id":1}}`,
+			want: ErrSnapshotMismatch,
+		},
+		{
+			name: "snapshot de otro curso",
+			body: `{"event_id":"a","schema_version":1,"operation":"UPDATE","course_id":1,"course":{"id":2}}`,
+			want: ErrSnapshotMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.body)); !errors.Is(err, tt.want) {
+				t.Errorf("Decode error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Los mensajes publicados antes de incluir el snapshot siguen siendo válidos: el
+// consumidor consulta el curso en la API de cursos
+func TestDecodeWithoutSnapshot(t *testing.T) {
+	for _, operation := range []Operation{OperationCreate, OperationUpdate} {
+		t.Run(string(operation), func(t *testing.T) {
+			body := `{"event_id":"a","schema_version":1,"operation":"` + string(operation) + `","course_id":3}`
+			got, err := Decode([]byte(body))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if got.Course != nil {
+				t.Errorf("Course = %+v, want nil", got.Course)
+			}
+		})
+	}
+}
+
+func TestEncodeRejectsInvalid(t *testing.T) {
+	event := validEvent()
+	event.Operation = "PATCH"
+	if _, err := Encode(event); !errors.Is(err, ErrUnknownOperation) {
+		t.Errorf("Encode error = %v, want %v", err, ErrUnknownOperation)
+	}
+}
@@ -0,0 +1,5 @@
+module events
+
+go 1.22
+
+require github.com/streadway/amqp v1.1.0
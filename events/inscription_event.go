@@ -0,0 +1,96 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InscriptionSchemaVersion versión actual del esquema de InscriptionEvent
+const InscriptionSchemaVersion = 1
+
+// InscriptionEventType tipo de cambio que describe un evento de inscripción
+type InscriptionEventType string
+
+const (
+	InscriptionCreated   InscriptionEventType = "inscription.created"
+	InscriptionWithdrawn InscriptionEventType = "inscription.withdrawn"
+	InscriptionCompleted InscriptionEventType = "inscription.completed"
+	InscriptionExpired   InscriptionEventType = "inscription.expired"
+	// InscriptionPromoted un estudiante de la lista de espera ocupó un cupo liberado
+	InscriptionPromoted InscriptionEventType = "inscription.promoted"
+)
+
+// InscriptionEvent mensaje publicado por inscriptions-api ante cada cambio de una
+// inscripción. Los cambios que liberan u ocupan un cupo modifican los cupos
+// disponibles del curso, que el evento informa ya calculados.
+type InscriptionEvent struct {
+	EventID       string               `json:"event_id"`
+	SchemaVersion int                  `json:"schema_version"`
+	Type          InscriptionEventType `json:"type"`
+	InscriptionID int64                `json:"inscription_id"`
+	UserID        int64                `json:"user_id"`
+	CourseID      int64                `json:"course_id"`
+	Timestamp     time.Time            `json:"timestamp"`
+	Reason        string               `json:"reason,omitempty"` // Motivo del retiro, solo en inscription.withdrawn
+	// Cupos disponibles del curso después del cambio. Vacío en los eventos
+	// anteriores al campo y en los cursos sin contador de cupos.
+	SeatsRemaining *int `json:"seats_remaining,omitempty"`
+	// Versión del contador de cupos que informa SeatsRemaining. Crece con cada
+	// cambio de cupos del curso y permite descartar los eventos que llegan
+	// desordenados o reintentados.
+	SeatsVersion int64 `json:"seats_version,omitempty"`
+}
+
+// Errores de validación del contrato de inscripciones
+var (
+	ErrUnknownInscriptionType = errors.New("unknown inscription event type")
+	ErrMissingInscriptionID   = errors.New("missing inscription id")
+	ErrNegativeSeats          = errors.New("negative seats remaining")
+)
+
+// Validate verifica que el evento cumpla con el contrato de la versión actual
+func (e InscriptionEvent) Validate() error {
+	if e.SchemaVersion < 1 || e.SchemaVersion > InscriptionSchemaVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.SchemaVersion)
+	}
+	if e.EventID == "" {
+		return ErrMissingEventID
+	}
+	switch e.Type {
+	case InscriptionCreated, InscriptionWithdrawn, InscriptionCompleted, InscriptionExpired, InscriptionPromoted:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownInscriptionType, e.Type)
+	}
+	if e.InscriptionID <= 0 {
+		return ErrMissingInscriptionID
+	}
+	if e.CourseID <= 0 {
+		return ErrMissingCourseID
+	}
+	if e.SeatsRemaining != nil && *e.SeatsRemaining < 0 {
+		return fmt.Errorf("%w: %d", ErrNegativeSeats, *e.SeatsRemaining)
+	}
+	return nil
+}
+
+// EncodeInscription valida y serializa el evento
+func EncodeInscription(event InscriptionEvent) ([]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid inscription event: %w", err)
+	}
+	return json.Marshal(event)
+}
+
+// DecodeInscription deserializa y valida un mensaje de inscripción
+func DecodeInscription(body []byte) (InscriptionEvent, error) {
+	var event InscriptionEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return InscriptionEvent{}, fmt.Errorf("invalid inscription event payload: %w", err)
+	}
+	if err := event.Validate(); err != nil {
+		return InscriptionEvent{}, fmt.Errorf("invalid inscription event: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func validInscription() InscriptionEvent {
+	seats := 3
+	return InscriptionEvent{
+		EventID:        "9f2c4e1a",
+		SchemaVersion:  InscriptionSchemaVersion,
+		Type:           InscriptionWithdrawn,
+		InscriptionID:  11,
+		UserID:         4,
+		CourseID:       7,
+		Timestamp:      time.Date(2024, 5, 24, 13, 0, 0, 0, time.UTC),
+		Reason:         "cambio de horario",
+		SeatsRemaining: &seats,
+		SeatsVersion:   5,
+	}
+}
+
+func TestEncodeDecodeInscriptionRoundTrip(t *testing.T) {
+	withoutSeats := validInscription()
+	withoutSeats.SeatsRemaining = nil
+	withoutSeats.SeatsVersion = 0
+
+	for name, event := range map[string]InscriptionEvent{
+		"con cupos": validInscription(),
+		"sin cupos": withoutSeats,
+	} {
+		t.Run(name, func(t *testing.T) {
+			body, err := EncodeInscription(event)
+			if err != nil {
+				t.Fatalf("EncodeInscription: %v", err)
+			}
+			got, err := DecodeInscription(body)
+			if err != nil {
+				t.Fatalf("DecodeInscription: %v", err)
+			}
+			if !reflect.DeepEqual(got, event) {
+				t.Errorf("DecodeInscription(EncodeInscription(e)) = %+v, want %+v", got, event)
+			}
+		})
+	}
+}
+
+func TestDecodeInscriptionRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{
+			name: "tipo desconocido",
+			body: `{"event_id":"a","schema_version":1,"type":"inscription.moved","inscription_id":1,"course_id":1}`,
+			want: ErrUnknownInscriptionType,
+		},
+		{
+			name: "sin inscription_id",
+			body: `{"event_id":"a","schema_version":1,"type":"inscription.created","course_id":1}`,
+			want: ErrMissingInscriptionID,
+		},
+		{
+			name: "sin course_id",
+			body: `{"event_id":"a","schema_version":1,"type":"inscription.created","inscription_id":1}`,
+			want: ErrMissingCourseID,
+		},
+		{
+			name: "cupos negativos",
+			body: `{"event_id":"a","schema_version":1,"type":"inscription.created","inscription_id":1,"course_id":1,"seats_remaining":-1}`,
+			want: ErrNegativeSeats,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeInscription([]byte(tt.body)); !errors.Is(err, tt.want) {
+				t.Errorf("DecodeInscription error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
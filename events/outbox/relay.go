@@ -0,0 +1,144 @@
+// Package outbox publica los eventos que cada servicio registra en su outbox,
+// en la misma transacción que el cambio que los origina.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Frecuencia con la que se eliminan los eventos ya publicados
+const cleanupInterval = time.Hour
+
+// ErrRejected lo envuelve publish cuando el evento nunca se va a poder publicar,
+// por ejemplo porque no cumple el contrato de eventos. El relay lo aparta sin
+// esperar a que agote sus intentos.
+var ErrRejected = errors.New("event rejected")
+
+// Store outbox de un servicio, con eventos del tipo E tal como se guardaron
+type Store[E any] interface {
+	// ClaimNext reserva durante lease el evento pendiente más antiguo que no esté
+	// reservado por otra instancia. Devuelve nil si no hay eventos para publicar.
+	ClaimNext(ctx context.Context, lease time.Duration) (*E, error)
+	// MarkSent marca el evento como publicado y libera su reserva
+	MarkSent(ctx context.Context, event E) error
+	// MarkFailed registra un intento fallido y libera la reserva. El evento sigue
+	// pendiente, salvo que dead indique que agotó sus intentos: en ese caso pasa a
+	// un estado fallido que ClaimNext ya no devuelve.
+	MarkFailed(ctx context.Context, event E, cause error, dead bool) error
+	// Attempts devuelve los intentos de publicación fallidos registrados en el evento
+	Attempts(event E) int
+	// DeleteSent elimina los eventos publicados antes de before
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Config configuración del relay del outbox
+type Config struct {
+	Interval  time.Duration // Frecuencia con la que se buscan eventos pendientes
+	MaxDelay  time.Duration // Espera máxima entre reintentos cuando la publicación falla
+	BatchSize int           // Cantidad máxima de eventos por iteración
+	// Intentos de publicación tras los que un evento se aparta como fallido, así un
+	// evento que nunca se puede publicar no frena a los siguientes. Debe cubrir una
+	// caída prolongada de RabbitMQ; los eventos apartados se conservan en el
+	// outbox. Con 0 se reintenta indefinidamente.
+	MaxAttempts int
+	// Tiempo durante el que un evento queda reservado por la instancia que lo
+	// publica, así varias réplicas no publican los mismos eventos
+	Lease time.Duration
+	// Tiempo que se conservan los eventos publicados antes de eliminarlos
+	Retention time.Duration
+}
+
+// Relay publica en RabbitMQ los eventos pendientes del outbox y los marca como enviados
+type Relay[E any] struct {
+	store   Store[E]
+	publish func(E) error
+	config  Config
+}
+
+// NewRelay constructor del relay. publish publica un evento y devuelve nil solo
+// si el broker lo confirmó.
+func NewRelay[E any](store Store[E], publish func(E) error, config Config) Relay[E] {
+	return Relay[E]{
+		store:   store,
+		publish: publish,
+		config:  config,
+	}
+}
+
+// Run procesa el outbox hasta que se cancela el contexto. Ante un error espera
+// cada vez más (hasta MaxDelay) antes de reintentar. Periódicamente elimina los
+// eventos publicados hace más de Retention.
+func (r Relay[E]) Run(ctx context.Context) {
+	failures := 0
+	var lastCleanup time.Time
+	for {
+		if time.Since(lastCleanup) >= cleanupInterval {
+			lastCleanup = time.Now()
+			r.cleanup(ctx)
+		}
+
+		delay := r.config.Interval
+		if err := r.relayPending(ctx); err != nil {
+			failures++
+			delay = time.Duration(failures) * r.config.Interval
+			if delay > r.config.MaxDelay {
+				delay = r.config.MaxDelay
+			}
+			log.Printf("Error al publicar eventos del outbox (intento %d): %v. Reintentando en %v...", failures, err, delay)
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// relayPending publica hasta BatchSize eventos, reservando cada uno antes de
+// publicarlo. Si la publicación falla la reserva se libera para reintentarlo, o el
+// evento se aparta si agotó MaxAttempts. Un evento puede publicarse más de una vez
+// si la instancia se detiene entre la publicación y MarkSent.
+func (r Relay[E]) relayPending(ctx context.Context) error {
+	for i := 0; i < r.config.BatchSize; i++ {
+		event, err := r.store.ClaimNext(ctx, r.config.Lease)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+
+		if err := r.publish(*event); err != nil {
+			attempts := r.store.Attempts(*event) + 1
+			dead := errors.Is(err, ErrRejected) || (r.config.MaxAttempts > 0 && attempts >= r.config.MaxAttempts)
+			if markErr := r.store.MarkFailed(ctx, *event, err, dead); markErr != nil {
+				log.Printf("Error al registrar el fallo de publicación: %v", markErr)
+			} else if dead {
+				log.Printf("Evento apartado del outbox tras %d intentos fallidos: %v", attempts, err)
+			}
+			return err
+		}
+		if err := r.store.MarkSent(ctx, *event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanup elimina los eventos publicados hace más de Retention
+func (r Relay[E]) cleanup(ctx context.Context) {
+	deleted, err := r.store.DeleteSent(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		log.Printf("Error al eliminar los eventos publicados del outbox: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Eliminados %d eventos publicados del outbox", deleted)
+	}
+}
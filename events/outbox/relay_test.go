@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memoryStore outbox en memoria con eventos identificados por un número
+type memoryStore struct {
+	pending  []int
+	claimed  map[int]bool
+	attempts map[int]int
+	sent     []int
+	failed   []int
+	dead     []int
+}
+
+func newMemoryStore(pending ...int) *memoryStore {
+	return &memoryStore{pending: pending, claimed: map[int]bool{}, attempts: map[int]int{}}
+}
+
+// remove quita el evento de los pendientes
+func (s *memoryStore) remove(event int) {
+	for i, pending := range s.pending {
+		if pending == event {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *memoryStore) ClaimNext(ctx context.Context, lease time.Duration) (*int, error) {
+	for _, event := range s.pending {
+		if !s.claimed[event] {
+			s.claimed[event] = true
+			return &event, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) MarkSent(ctx context.Context, event int) error {
+	s.sent = append(s.sent, event)
+	delete(s.claimed, event)
+	s.remove(event)
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, event int, cause error, dead bool) error {
+	s.failed = append(s.failed, event)
+	s.attempts[event]++
+	delete(s.claimed, event)
+	if dead {
+		s.dead = append(s.dead, event)
+		s.remove(event)
+	}
+	return nil
+}
+
+func (s *memoryStore) Attempts(event int) int {
+	return s.attempts[event]
+}
+
+func (s *memoryStore) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRelayPendingPublishesInOrder(t *testing.T) {
+	store := newMemoryStore(1, 2, 3)
+	var published []int
+	relay := NewRelay[int](store, func(event int) error {
+		published = append(published, event)
+		return nil
+	}, Config{BatchSize: 2})
+
+	if err := relay.relayPending(context.Background()); err != nil {
+		t.Fatalf("relayPending: %v", err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(published, want) || !reflect.DeepEqual(store.sent, want) {
+		t.Errorf("publicados = %v, enviados = %v, want %v", published, store.sent, want)
+	}
+	if err := relay.relayPending(context.Background()); err != nil {
+		t.Fatalf("relayPending: %v", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(store.sent, want) {
+		t.Errorf("enviados = %v, want %v", store.sent, want)
+	}
+}
+
+// Si la publicación falla el evento queda pendiente y sin reserva, y el lote se
+// corta para reintentarlo antes que los siguientes
+func TestRelayPendingStopsOnPublishError(t *testing.T) {
+	store := newMemoryStore(1, 2)
+	failure := errors.New("sin conexión")
+	relay := NewRelay[int](store, func(event int) error {
+		return failure
+	}, Config{BatchSize: 10})
+
+	if err := relay.relayPending(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("relayPending error = %v, want %v", err, failure)
+	}
+	if !reflect.DeepEqual(store.failed, []int{1}) || len(store.sent) != 0 {
+		t.Errorf("fallidos = %v, enviados = %v, want [1] y ninguno", store.failed, store.sent)
+	}
+	if store.claimed[1] {
+		t.Error("el evento 1 sigue reservado")
+	}
+}
+
+// Un evento que nunca se puede publicar se aparta al agotar sus intentos y deja
+// de frenar a los siguientes
+func TestRelayPendingSetsPoisonEventAside(t *testing.T) {
+	store := newMemoryStore(1, 2)
+	rejected := errors.New("el evento no cumple el contrato")
+	var published []int
+	relay := NewRelay[int](store, func(event int) error {
+		if event == 1 {
+			return rejected
+		}
+		published = append(published, event)
+		return nil
+	}, Config{BatchSize: 10, MaxAttempts: 3})
+
+	for i := 0; i < 3; i++ {
+		if err := relay.relayPending(context.Background()); !errors.Is(err, rejected) {
+			t.Fatalf("relayPending %d error = %v, want %v", i+1, err, rejected)
+		}
+	}
+	if !reflect.DeepEqual(store.dead, []int{1}) {
+		t.Fatalf("apartados = %v, want [1]", store.dead)
+	}
+	if err := relay.relayPending(context.Background()); err != nil {
+		t.Fatalf("relayPending: %v", err)
+	}
+	if !reflect.DeepEqual(published, []int{2}) || !reflect.DeepEqual(store.sent, []int{2}) {
+		t.Errorf("publicados = %v, enviados = %v, want [2]", published, store.sent)
+	}
+}
+
+// Un evento rechazado se aparta en el primer intento
+func TestRelayPendingSetsRejectedEventAside(t *testing.T) {
+	store := newMemoryStore(1)
+	relay := NewRelay[int](store, func(event int) error {
+		return fmt.Errorf("%w: falta el curso", ErrRejected)
+	}, Config{BatchSize: 10})
+
+	if err := relay.relayPending(context.Background()); !errors.Is(err, ErrRejected) {
+		t.Fatalf("relayPending error = %v, want %v", err, ErrRejected)
+	}
+	if !reflect.DeepEqual(store.dead, []int{1}) {
+		t.Errorf("apartados = %v, want [1]", store.dead)
+	}
+}
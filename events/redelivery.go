@@ -0,0 +1,167 @@
+package events
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers usados para llevar el historial de reintentos de cada mensaje
+const (
+	AttemptsHeader  = "x-attempts"
+	LastErrorHeader = "x-last-error"
+)
+
+// Tiempo máximo de espera de la confirmación del broker para cada mensaje
+const ConfirmTimeout = 5 * time.Second
+
+// Publisher publica un mensaje y devuelve nil solo si el broker lo confirmó
+type Publisher interface {
+	Publish(exchange, key string, msg amqp.Publishing) error
+}
+
+// ConfirmChannel canal en modo confirmación que publica de a un mensaje,
+// esperando la confirmación del broker antes del siguiente
+type ConfirmChannel struct {
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	broken   bool
+}
+
+// NewConfirmChannel habilita las confirmaciones en el canal
+func NewConfirmChannel(channel *amqp.Channel) (*ConfirmChannel, error) {
+	if err := channel.Confirm(false); err != nil {
+		return nil, err
+	}
+	return &ConfirmChannel{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+	}, nil
+}
+
+// Publish publica el mensaje y espera su confirmación. Si el canal falla o la
+// confirmación no llega a tiempo el canal queda inutilizable (ver Broken), porque
+// una confirmación tardía desincronizaría las siguientes.
+func (c *ConfirmChannel) Publish(exchange, key string, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken {
+		return errors.New("el canal de RabbitMQ ya no es utilizable")
+	}
+
+	if err := c.channel.Publish(exchange, key, false, false, msg); err != nil {
+		c.broken = true
+		return err
+	}
+	select {
+	case confirmation, ok := <-c.confirms:
+		if !ok {
+			c.broken = true
+			return errors.New("el canal de RabbitMQ se cerró antes de confirmar el mensaje")
+		}
+		if !confirmation.Ack {
+			// El broker no pudo guardar el mensaje, el canal sigue siendo válido
+			return errors.New("RabbitMQ rechazó el mensaje")
+		}
+		return nil
+	case <-time.After(ConfirmTimeout):
+		c.broken = true
+		return errors.New("tiempo de espera agotado esperando la confirmación de RabbitMQ")
+	}
+}
+
+// Broken indica si el canal debe reemplazarse por uno nuevo
+func (c *ConfirmChannel) Broken() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.broken
+}
+
+// Close cierra el canal
+func (c *ConfirmChannel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.broken = true
+	return c.channel.Close()
+}
+
+// Attempts devuelve la cantidad de intentos fallidos registrados en el mensaje
+func Attempts(msg amqp.Delivery) int {
+	switch value := msg.Headers[AttemptsHeader].(type) {
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case int:
+		return value
+	}
+	return 0
+}
+
+// Retry envía el mensaje que no se pudo procesar a la cola de reintentos que
+// corresponde a su número de intento, o a la cola de mensajes muertos si agotó
+// los intentos
+func (t Topology) Retry(publisher Publisher, msg amqp.Delivery, eventID string, cause error) {
+	attempts := Attempts(msg) + 1
+	if attempts >= t.MaxAttempts {
+		log.Printf("Evento %s descartado tras %d intentos: %v", eventID, attempts, cause)
+		forward(publisher, msg, t.DeadLetterQueue(), attempts, cause)
+		return
+	}
+	log.Printf("Error al procesar el evento %s (intento %d): %v", eventID, attempts, cause)
+	forward(publisher, msg, t.RetryQueue(attempts), attempts, cause)
+}
+
+// DeadLetter envía a la cola de mensajes muertos un mensaje que no cumple el
+// contrato de eventos, ya que reintentarlo no serviría
+func (t Topology) DeadLetter(publisher Publisher, msg amqp.Delivery, cause error) {
+	log.Printf("Error al deserializar el mensaje: %v", cause)
+	forward(publisher, msg, t.DeadLetterQueue(), Attempts(msg), cause)
+}
+
+// Replay devuelve un mensaje muerto a la cola principal con los intentos
+// reiniciados. Se publica por el exchange por defecto directo a la cola, ya que
+// por el exchange de la topología lo recibirían también las otras colas enlazadas
+// con la misma clave.
+func (t Topology) Replay(publisher Publisher, msg amqp.Delivery) error {
+	return publisher.Publish("", t.Queue, amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Body:         msg.Body,
+	})
+}
+
+// forward publica una copia del mensaje en la cola indicada y confirma el original
+// recién cuando el broker confirma la copia. Si la publicación falla el mensaje
+// vuelve a la cola principal para no perderlo.
+func forward(publisher Publisher, msg amqp.Delivery, queue string, attempts int, cause error) {
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	headers[AttemptsHeader] = int32(attempts)
+	headers[LastErrorHeader] = cause.Error()
+
+	err := publisher.Publish("", queue, amqp.Publishing{
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.MessageId,
+		Headers:      headers,
+		Body:         msg.Body,
+	})
+	if err != nil {
+		log.Printf("Error al reenviar el mensaje a %s: %v", queue, err)
+		if err := msg.Nack(false, true); err != nil {
+			log.Printf("Error al devolver el mensaje a la cola: %v", err)
+		}
+		return
+	}
+	if err := msg.Ack(false); err != nil {
+		log.Printf("Error al confirmar el mensaje: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+// published registra las publicaciones; si fail no es nil las rechaza
+type published struct {
+	exchange, key string
+	msg           amqp.Publishing
+	calls         int
+	fail          error
+}
+
+func (p *published) Publish(exchange, key string, msg amqp.Publishing) error {
+	p.calls++
+	p.exchange, p.key, p.msg = exchange, key, msg
+	return p.fail
+}
+
+// acknowledger registra cómo se confirmó la entrega
+type acknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func delivery(attempts interface{}) (amqp.Delivery, *acknowledger) {
+	ack := &acknowledger{}
+	msg := amqp.Delivery{Acknowledger: ack, MessageId: "m1", Body: []byte(`{}`), Headers: amqp.Table{"trace": "t1"}}
+	if attempts != nil {
+		msg.Headers[AttemptsHeader] = attempts
+	}
+	return msg, ack
+}
+
+func TestRetryRoutesByAttempt(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  interface{}
+		wantQueue string
+		wantCount int32
+	}{
+		{"primer fallo", nil, CoursesTopology.RetryQueue(1), 1},
+		{"intento int32", int32(2), CoursesTopology.RetryQueue(3), 3},
+		{"intento int64", int64(3), CoursesTopology.RetryQueue(4), 4},
+		{"agota los intentos", int32(4), CoursesTopology.DeadLetterQueue(), 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &published{}
+			msg, ack := delivery(tt.attempts)
+			CoursesTopology.Retry(publisher, msg, "e1", errors.New("boom"))
+
+			if publisher.exchange != "" || publisher.key != tt.wantQueue {
+				t.Errorf("publicado en (%q, %q), want (\"\", %q)", publisher.exchange, publisher.key, tt.wantQueue)
+			}
+			headers := publisher.msg.Headers
+			if headers[AttemptsHeader] != tt.wantCount || headers[LastErrorHeader] != "boom" || headers["trace"] != "t1" {
+				t.Errorf("headers = %v", headers)
+			}
+			if publisher.msg.DeliveryMode != amqp.Persistent {
+				t.Errorf("DeliveryMode = %d, want persistente", publisher.msg.DeliveryMode)
+			}
+			if !ack.acked || ack.nacked {
+				t.Errorf("acked = %v, nacked = %v, want solo ack", ack.acked, ack.nacked)
+			}
+		})
+	}
+}
+
+// Si la copia no se confirma, el original vuelve a la cola en lugar de perderse
+func TestRetryRequeuesWhenPublishFails(t *testing.T) {
+	publisher := &published{fail: errors.New("sin confirmación")}
+	msg, ack := delivery(nil)
+	CoursesTopology.Retry(publisher, msg, "e1", errors.New("boom"))
+
+	if ack.acked || !ack.nacked || !ack.requeued {
+		t.Errorf("acked = %v, nacked = %v, requeued = %v, want nack con requeue", ack.acked, ack.nacked, ack.requeued)
+	}
+}
+
+func TestDeadLetterKeepsAttempts(t *testing.T) {
+	publisher := &published{}
+	msg, ack := delivery(int32(2))
+	InscriptionsTopology.DeadLetter(publisher, msg, ErrUnknownOperation)
+
+	if publisher.key != InscriptionsTopology.DeadLetterQueue() {
+		t.Errorf("publicado en %q, want %q", publisher.key, InscriptionsTopology.DeadLetterQueue())
+	}
+	if publisher.msg.Headers[AttemptsHeader] != int32(2) {
+		t.Errorf("intentos = %v, want 2", publisher.msg.Headers[AttemptsHeader])
+	}
+	if !ack.acked {
+		t.Error("el mensaje original no se confirmó")
+	}
+}
+
+// Replay publica directo en la cola, así no lo reciben las otras colas enlazadas
+// al exchange de cursos
+func TestReplayTargetsOnlyTheQueue(t *testing.T) {
+	publisher := &published{}
+	msg, _ := delivery(int32(5))
+	if err := CoursesTopology.Replay(publisher, msg); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if publisher.exchange != "" || publisher.key != CoursesTopology.Queue {
+		t.Errorf("publicado en (%q, %q), want (\"\", %q)", publisher.exchange, publisher.key, CoursesTopology.Queue)
+	}
+	if _, ok := publisher.msg.Headers[AttemptsHeader]; ok {
+		t.Errorf("headers = %v, want los intentos reiniciados", publisher.msg.Headers)
+	}
+}
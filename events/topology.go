@@ -0,0 +1,93 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Topology describe el exchange y las colas de RabbitMQ por las que viajan los
+// eventos. Productor y consumidor la declaran con los mismos
+// parámetros, ya que RabbitMQ rechaza redeclarar una cola con otros argumentos.
+type Topology struct {
+	Exchange    string
+	Queue       string
+	RoutingKey  string
+	MaxAttempts int           // Intentos antes de enviar el mensaje a la cola de mensajes muertos
+	RetryDelay  time.Duration // Espera antes del primer reintento, se duplica en cada intento
+}
+
+// CoursesTopology topología usada por courses-api y search-api
+var CoursesTopology = Topology{
+	Exchange:    "courses",
+	Queue:       "courses_queue",
+	RoutingKey:  "course.event",
+	MaxAttempts: 5,
+	RetryDelay:  5 * time.Second,
+}
+
+// WaitlistTopology cola propia de inscriptions-api sobre el exchange de cursos,
+// usada para promover la lista de espera cuando aumenta la capacidad de un curso
+var WaitlistTopology = Topology{
+	Exchange:    "courses",
+	Queue:       "inscriptions_courses_queue",
+	RoutingKey:  "course.event",
+	MaxAttempts: 5,
+	RetryDelay:  5 * time.Second,
+}
+
+// InscriptionsTopology topología de los eventos de inscripciones, publicados por
+// inscriptions-api y consumidos por search-api para actualizar los cupos disponibles
+var InscriptionsTopology = Topology{
+	Exchange:    "inscriptions",
+	Queue:       "inscriptions_queue",
+	RoutingKey:  "inscription.event",
+	MaxAttempts: 5,
+	RetryDelay:  5 * time.Second,
+}
+
+// RetryQueue cola de espera para el reintento número attempt. Cada una tiene un
+// TTL fijo y al expirar devuelve los mensajes a la cola principal.
+func (t Topology) RetryQueue(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", t.Queue, attempt)
+}
+
+// DeadLetterQueue cola donde quedan los mensajes que no se pudieron procesar
+func (t Topology) DeadLetterQueue() string {
+	return t.Queue + ".dead"
+}
+
+// Declare declara de forma idempotente el exchange, la cola principal, las colas
+// de reintento y la de mensajes muertos, todas durables.
+func (t Topology) Declare(channel *amqp.Channel) error {
+	if err := channel.ExchangeDeclare(t.Exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring exchange %s: %w", t.Exchange, err)
+	}
+
+	if _, err := channel.QueueDeclare(t.Queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring queue %s: %w", t.Queue, err)
+	}
+	if err := channel.QueueBind(t.Queue, t.RoutingKey, t.Exchange, false, nil); err != nil {
+		return fmt.Errorf("error binding queue %s: %w", t.Queue, err)
+	}
+
+	// Colas de reintento con backoff exponencial, al expirar vuelven a la cola principal
+	delay := t.RetryDelay
+	for attempt := 1; attempt < t.MaxAttempts; attempt++ {
+		_, err := channel.QueueDeclare(t.RetryQueue(attempt), true, false, false, false, amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": t.Queue,
+		})
+		if err != nil {
+			return fmt.Errorf("error declaring retry queue %d: %w", attempt, err)
+		}
+		delay *= 2
+	}
+
+	if _, err := channel.QueueDeclare(t.DeadLetterQueue(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declaring dead letter queue: %w", err)
+	}
+	return nil
+}
@@ -1,13 +1,58 @@
 package dao
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type InscriptionModel struct {
-	ID       uint `gorm:"primaryKey;autoIncrement"`
-	UserID   uint `gorm:"not null;index"`
-	CourseID uint `gorm:"not null;index"`
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	// Un usuario tiene una sola inscripción que ocupe cupo (pendiente o activa) por
+	// curso, aunque lleguen solicitudes concurrentes. Cuando la inscripción deja de
+	// ocupar cupo Active pasa a NULL, que MySQL no considera en el índice único, y
+	// el usuario puede volver a inscribirse conservando el historial.
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_user_course_active"`
+	CourseID       uint      `gorm:"not null;uniqueIndex:idx_user_course_active;index"`
+	Active         *bool     `gorm:"default:true;uniqueIndex:idx_user_course_active"`
+	Status         string    `gorm:"size:16;not null;default:active;index"`
+	CreatedAt      time.Time `gorm:"index"` // NULL solo en filas anteriores a los estados
+	UpdatedAt      time.Time
+	CompletedAt    *time.Time
+	WithdrawnAt    *time.Time
+	WithdrawReason string `gorm:"size:255"`
+}
+
+// CourseSeatsModel contador de cupos de un curso. Las inscripciones bloquean su
+// fila con SELECT ... FOR UPDATE, de modo que no se puedan ocupar más cupos que
+// la capacidad aunque lleguen solicitudes concurrentes.
+type CourseSeatsModel struct {
+	CourseID uint `gorm:"primaryKey;autoIncrement:false"`
+	Capacity int  `gorm:"not null"`
+	Taken    int  `gorm:"not null"`
+	// Crece con cada evento que informa los cupos del curso, así los consumidores
+	// reconocen los eventos desordenados
+	Version int64 `gorm:"not null;default:0"`
+	// Versión del snapshot del curso que fijó la capacidad; los snapshots
+	// anteriores a ella llegan desordenados y se descartan
+	CourseVersion int64 `gorm:"not null;default:0"`
+}
+
+func (CourseSeatsModel) TableName() string {
+	return "course_seats"
+}
+
+// WaitlistModel lugar en la lista de espera de un curso lleno. El ID autoincremental
+// define el orden de llegada: se promueve primero el de menor ID.
+type WaitlistModel struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_waitlist_user_course"`
+	CourseID  uint      `gorm:"not null;uniqueIndex:idx_waitlist_user_course;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (WaitlistModel) TableName() string {
+	return "course_waitlist"
 }
 
 type InscriptionDAO struct {
@@ -0,0 +1,41 @@
+package dao
+
+import "time"
+
+// Estados de un evento del outbox
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed" // Agotó sus intentos de publicación, el relay no lo vuelve a tomar
+)
+
+// EventModel evento de inscripción pendiente de publicar en RabbitMQ. Se registra
+// en la misma transacción que el cambio de la inscripción, así ningún cambio queda
+// sin su evento aunque RabbitMQ no esté disponible.
+type EventModel struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	EventID       string `gorm:"size:32;not null;uniqueIndex"` // Se mantiene entre reintentos de publicación
+	Type          string `gorm:"size:32;not null"`             // events.InscriptionEventType del evento a publicar
+	InscriptionID uint   `gorm:"not null"`
+	UserID        uint   `gorm:"not null"`
+	CourseID      uint   `gorm:"not null"`
+	Reason        string `gorm:"size:255"`
+	Status        string `gorm:"size:16;not null;index:idx_outbox_status_sent"`
+	Attempts      int    `gorm:"not null"`
+	LastError     string `gorm:"size:1024"`
+	CreatedAt     time.Time
+	SentAt        *time.Time `gorm:"index:idx_outbox_status_sent"`
+	// Reserva de una instancia del relay: hasta cuándo vale y quién la tomó. Vencido
+	// ese plazo otra instancia puede tomar el evento.
+	ClaimedUntil *time.Time
+	ClaimToken   string `gorm:"size:32"`
+
+	// Cupos disponibles del curso después del cambio, nil si no tiene contador, y
+	// versión del contador que los informa
+	SeatsRemaining *int
+	SeatsVersion   int64 `gorm:"not null;default:0"`
+}
+
+func (EventModel) TableName() string {
+	return "inscription_outbox"
+}
@@ -44,8 +44,9 @@ func (c *HTTPClient) CheckCourseExists(courseID uint) error {
 }
 
 type CourseDetails struct {
-	ID       uint `json:"id"`
-	Capacity int  `json:"capacity"`
+	ID       uint  `json:"id"`
+	Capacity int   `json:"capacity"`
+	Version  int64 `json:"version"`
 	// Add other fields if needed
 }
 
@@ -0,0 +1,257 @@
+package rabbit
+
+import (
+	"errors"
+	"events"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Espera máxima entre intentos de reconexión del consumidor
+const maxReconnectDelay = 30 * time.Second
+
+type RabbitConfig struct {
+	URI string
+	// Topología en la que se publican los eventos de inscripciones
+	Publish events.Topology
+	// Topología de la que se consumen los eventos de cursos
+	Consume events.Topology
+}
+
+// Rabbit publica eventos de inscripciones con confirmación del broker y consume
+// eventos de cursos. Publicador y consumidor usan conexiones separadas, así
+// descartar una no corta a la otra. Si la conexión se pierde, el siguiente
+// Publish y el consumidor vuelven a conectarse.
+type Rabbit struct {
+	config RabbitConfig
+
+	publishMu  sync.Mutex
+	publishing *amqp.Connection
+	publisher  *events.ConfirmChannel
+
+	consumeMu sync.Mutex
+	closed    bool
+	consuming *amqp.Connection
+}
+
+// consumer conexión del consumidor con sus entregas y el canal por el que reenvía
+// los mensajes a las colas de reintentos y de mensajes muertos
+type consumer struct {
+	connection *amqp.Connection
+	messages   <-chan amqp.Delivery
+	forwarder  *events.ConfirmChannel
+}
+
+func NewRabbit(config RabbitConfig) (*Rabbit, error) {
+	r := &Rabbit{config: config}
+	if err := r.connectPublisher(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// connectPublisher abre la conexión de publicación con un canal en modo
+// confirmación y declara la topología de inscripciones. Debe llamarse con
+// publishMu tomado (o antes de compartir la instancia).
+func (r *Rabbit) connectPublisher() error {
+	connection, err := amqp.Dial(r.config.URI)
+	if err != nil {
+		return fmt.Errorf("error getting Rabbit connection: %w", err)
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return fmt.Errorf("error creating Rabbit channel: %w", err)
+	}
+	if err := r.config.Publish.Declare(channel); err != nil {
+		connection.Close()
+		return fmt.Errorf("error declaring Rabbit topology: %w", err)
+	}
+	publisher, err := events.NewConfirmChannel(channel)
+	if err != nil {
+		connection.Close()
+		return fmt.Errorf("error enabling Rabbit publisher confirms: %w", err)
+	}
+
+	r.publishing = connection
+	r.publisher = publisher
+	return nil
+}
+
+// resetPublisher descarta la conexión de publicación para que el próximo Publish
+// se reconecte. Debe llamarse con publishMu tomado.
+func (r *Rabbit) resetPublisher() {
+	if r.publishing != nil {
+		r.publishing.Close()
+	}
+	r.publishing = nil
+	r.publisher = nil
+}
+
+// Publish publica el evento como mensaje persistente y espera la confirmación del broker
+func (r *Rabbit) Publish(event events.InscriptionEvent) error {
+	bytes, err := events.EncodeInscription(event)
+	if err != nil {
+		return fmt.Errorf("error al serializar InscriptionEvent: %w", err)
+	}
+
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+
+	if r.publishing == nil || r.publishing.IsClosed() {
+		log.Println("Reconectando a RabbitMQ...")
+		r.resetPublisher()
+		if err := r.connectPublisher(); err != nil {
+			return fmt.Errorf("error al reconectar con Rabbit: %w", err)
+		}
+	}
+
+	err = r.publisher.Publish(r.config.Publish.Exchange, r.config.Publish.RoutingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    event.EventID,
+		Timestamp:    event.Timestamp,
+		Body:         bytes,
+	})
+	if err != nil {
+		if r.publisher.Broken() {
+			r.resetPublisher()
+		}
+		return fmt.Errorf("error al publicar en Rabbit: %w", err)
+	}
+	return nil
+}
+
+// Handler procesa un evento de cursos, si devuelve error el mensaje se reintenta
+type Handler func(events.CourseEvent) error
+
+// StartConsumer inicia la escucha de eventos de cursos. Cada mensaje se confirma
+// solo si el handler lo procesó sin error; si falla se reintenta con backoff y,
+// agotados los intentos, se envía a la cola de mensajes muertos.
+func (r *Rabbit) StartConsumer(handler Handler) error {
+	current, err := r.consume()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			for msg := range current.messages {
+				r.handle(current, msg, handler)
+			}
+
+			// El canal de entregas se cierra cuando se pierde la conexión o el canal
+			var ok bool
+			if current, ok = r.reconnect(); !ok {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// consume abre la conexión del consumidor, declara la topología de cursos y
+// registra el consumidor sobre su cola
+func (r *Rabbit) consume() (consumer, error) {
+	connection, err := amqp.Dial(r.config.URI)
+	if err != nil {
+		return consumer{}, fmt.Errorf("error getting Rabbit connection: %w", err)
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return consumer{}, fmt.Errorf("error creating Rabbit channel: %w", err)
+	}
+	if err := r.config.Consume.Declare(channel); err != nil {
+		connection.Close()
+		return consumer{}, fmt.Errorf("error declaring Rabbit topology: %w", err)
+	}
+	forwarderChannel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return consumer{}, fmt.Errorf("error creating Rabbit channel: %w", err)
+	}
+	forwarder, err := events.NewConfirmChannel(forwarderChannel)
+	if err != nil {
+		connection.Close()
+		return consumer{}, fmt.Errorf("error enabling Rabbit publisher confirms: %w", err)
+	}
+	messages, err := channel.Consume(r.config.Consume.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		connection.Close()
+		return consumer{}, fmt.Errorf("error al registrar el consumidor: %w", err)
+	}
+
+	r.consumeMu.Lock()
+	defer r.consumeMu.Unlock()
+	if r.closed {
+		connection.Close()
+		return consumer{}, errors.New("la conexión con Rabbit se cerró")
+	}
+	r.consuming = connection
+	return consumer{connection: connection, messages: messages, forwarder: forwarder}, nil
+}
+
+// reconnect reintenta registrar el consumidor con backoff hasta lograrlo.
+// Devuelve false si mientras tanto se llamó a Close.
+func (r *Rabbit) reconnect() (consumer, bool) {
+	delay := time.Second
+	for !r.isClosed() {
+		current, err := r.consume()
+		if err == nil {
+			log.Println("Reconectado a RabbitMQ")
+			return current, true
+		}
+
+		log.Printf("Error al reconectar con RabbitMQ: %v. Reintentando en %v...", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	return consumer{}, false
+}
+
+func (r *Rabbit) isClosed() bool {
+	r.consumeMu.Lock()
+	defer r.consumeMu.Unlock()
+	return r.closed
+}
+
+func (r *Rabbit) handle(current consumer, msg amqp.Delivery, handler Handler) {
+	topology := r.config.Consume
+	event, err := events.Decode(msg.Body)
+	if err != nil {
+		// Un mensaje que no cumple el contrato no se va a poder procesar reintentando
+		topology.DeadLetter(current.forwarder, msg, err)
+	} else if err := handler(event); err != nil {
+		topology.Retry(current.forwarder, msg, event.EventID, err)
+	} else if err := msg.Ack(false); err != nil {
+		log.Printf("Error al confirmar el mensaje: %v", err)
+	}
+
+	// Sin canal de reenvío los mensajes que fallen no podrían reintentarse; se cierra
+	// la conexión para que el consumidor se reconecte con uno nuevo
+	if current.forwarder.Broken() {
+		current.connection.Close()
+	}
+}
+
+// Close cierra las conexiones con RabbitMQ y detiene el consumidor
+func (r *Rabbit) Close() {
+	r.publishMu.Lock()
+	r.resetPublisher()
+	r.publishMu.Unlock()
+
+	r.consumeMu.Lock()
+	defer r.consumeMu.Unlock()
+	r.closed = true
+	if r.consuming != nil {
+		r.consuming.Close()
+	}
+}
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	domain "inscriptions-api/domain/inscriptions"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,12 +14,20 @@ import (
 )
 
 type Service interface {
-	CreateInscription(ctx context.Context, userID, courseID uint) (*domain.Inscription, error)
-	GetInscriptions(ctx context.Context) ([]domain.Inscription, error)
-	GetInscriptionsByUser(ctx context.Context, userID uint) ([]domain.Inscription, error)
-	GetInscriptionsByCourse(ctx context.Context, courseID uint) ([]domain.Inscription, error)
+	CreateInscription(ctx context.Context, userID, courseID uint, status domain.Status) (domain.Enrollment, error)
+	GetInscriptions(ctx context.Context, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByUser(ctx context.Context, userID uint, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByCourse(ctx context.Context, courseID uint, statuses []domain.Status) ([]domain.Inscription, error)
+	UpdateStatus(ctx context.Context, id uint, status domain.Status) (domain.StatusChange, error)
+	WithdrawInscription(ctx context.Context, id uint, reason string) (domain.StatusChange, error)
+	WithdrawFromCourse(ctx context.Context, userID, courseID uint, reason string) (domain.StatusChange, error)
+	GetWaitlistByCourse(ctx context.Context, courseID uint) ([]domain.WaitlistEntry, error)
+	GetWaitlistByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error)
 }
 
+// Longitud máxima del motivo de retiro, la de la columna withdraw_reason
+const maxReasonLength = 255
+
 type Controller struct {
 	service Service
 }
@@ -28,29 +38,183 @@ func NewController(service Service) *Controller {
 
 func (ctrl *Controller) CreateInscription(c *gin.Context) {
 	var req struct {
-		UserID   uint `json:"user_id" binding:"required"`
-		CourseID uint `json:"course_id" binding:"required"`
+		UserID   uint   `json:"user_id" binding:"required"`
+		CourseID uint   `json:"course_id" binding:"required"`
+		Status   string `json:"status"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid format: %s", err.Error())})
 		return
 	}
+	initial := domain.StatusActive
+	if req.Status != "" {
+		var err error
+		if initial, err = domain.ParseStatus(req.Status); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	inscription, err := ctrl.service.CreateInscription(c.Request.Context(), req.UserID, req.CourseID)
+	enrollment, err := ctrl.service.CreateInscription(c.Request.Context(), req.UserID, req.CourseID, initial)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "user does not exist" || err.Error() == "course does not exist" {
 			status = http.StatusNotFound
 		}
+		if errors.Is(err, domain.ErrInvalidStatus) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, domain.ErrAlreadyEnrolled) || errors.Is(err, domain.ErrAlreadyWaiting) {
+			status = http.StatusConflict
+		}
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, inscription)
+	// Con el curso lleno la solicitud queda pendiente en la lista de espera
+	if enrollment.Waitlist != nil {
+		c.JSON(http.StatusAccepted, enrollment.Waitlist)
+		return
+	}
+	c.JSON(http.StatusCreated, enrollment.Inscription)
+}
+
+func (ctrl *Controller) UpdateStatus(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid inscription ID: %s", idParam)})
+		return
+	}
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid format: %s", err.Error())})
+		return
+	}
+	status, err := domain.ParseStatus(req.Status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := ctrl.service.UpdateStatus(c.Request.Context(), uint(id), status)
+	if err != nil {
+		c.JSON(statusChangeCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, change.Inscription)
+}
+
+func (ctrl *Controller) WithdrawInscription(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid inscription ID: %s", idParam)})
+		return
+	}
+	reason, ok := withdrawReason(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := ctrl.service.WithdrawInscription(c.Request.Context(), uint(id), reason)
+	if err != nil {
+		c.JSON(statusChangeCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, withdrawal.Inscription)
+}
+
+func (ctrl *Controller) WithdrawFromCourse(c *gin.Context) {
+	userIDParam := strings.TrimSpace(c.Param("userID"))
+	userID, err := strconv.ParseUint(userIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid user ID: %s", userIDParam)})
+		return
+	}
+	courseIDParam := c.Param("courseID")
+	courseID, err := strconv.ParseUint(courseIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid course ID: %s", courseIDParam)})
+		return
+	}
+	reason, ok := withdrawReason(c)
+	if !ok {
+		return
+	}
+
+	withdrawal, err := ctrl.service.WithdrawFromCourse(c.Request.Context(), uint(userID), uint(courseID), reason)
+	if err != nil {
+		c.JSON(statusChangeCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	// Si el usuario solo estaba en la lista de espera no hay inscripción que devolver
+	if withdrawal.Inscription == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, withdrawal.Inscription)
+}
+
+// withdrawReason lee el motivo opcional del cuerpo de la solicitud de retiro.
+// Si es inválido responde 400 y devuelve false.
+func withdrawReason(c *gin.Context) (string, bool) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// El cuerpo es opcional: sin cuerpo el retiro queda sin motivo
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid format: %s", err.Error())})
+		return "", false
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if len(reason) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reason must be at most %d characters", maxReasonLength)})
+		return "", false
+	}
+	return reason, true
+}
+
+func statusChangeCode(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidTransition):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// statusFilter lee el filtro opcional ?status=, con uno o más estados separados por
+// coma. Sin filtro se devuelven todas las inscripciones. Si algún estado es
+// inválido responde 400 y devuelve false.
+func statusFilter(c *gin.Context) ([]domain.Status, bool) {
+	param := strings.TrimSpace(c.Query("status"))
+	if param == "" {
+		return nil, true
+	}
+
+	var statuses []domain.Status
+	for _, value := range strings.Split(param, ",") {
+		status, err := domain.ParseStatus(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, true
 }
 
 func (ctrl *Controller) GetInscriptions(c *gin.Context) {
-	inscriptions, err := ctrl.service.GetInscriptions(c.Request.Context())
+	statuses, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+
+	inscriptions, err := ctrl.service.GetInscriptions(c.Request.Context(), statuses)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error: %s", err.Error())})
 		return
@@ -66,7 +230,12 @@ func (ctrl *Controller) GetInscriptionsByUser(c *gin.Context) {
 		return
 	}
 
-	inscriptions, err := ctrl.service.GetInscriptionsByUser(c.Request.Context(), uint(userID))
+	statuses, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+
+	inscriptions, err := ctrl.service.GetInscriptionsByUser(c.Request.Context(), uint(userID), statuses)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -82,10 +251,47 @@ func (ctrl *Controller) GetInscriptionsByCourse(c *gin.Context) {
 		return
 	}
 
-	inscriptions, err := ctrl.service.GetInscriptionsByCourse(c.Request.Context(), uint(courseID))
+	statuses, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+
+	inscriptions, err := ctrl.service.GetInscriptionsByCourse(c.Request.Context(), uint(courseID), statuses)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, inscriptions)
 }
+
+func (ctrl *Controller) GetWaitlistByCourse(c *gin.Context) {
+	courseIDParam := c.Param("courseID")
+	courseID, err := strconv.ParseUint(courseIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid course ID: %s", courseIDParam)})
+		return
+	}
+
+	waitlist, err := ctrl.service.GetWaitlistByCourse(c.Request.Context(), uint(courseID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, waitlist)
+}
+
+func (ctrl *Controller) GetWaitlistByUser(c *gin.Context) {
+	userIDParam := strings.TrimSpace(c.Param("userID"))
+	userID, err := strconv.ParseUint(userIDParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid user ID: %s", userIDParam)})
+		return
+	}
+
+	waitlist, err := ctrl.service.GetWaitlistByUser(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, waitlist)
+}
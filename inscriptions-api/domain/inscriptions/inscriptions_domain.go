@@ -1,7 +1,100 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAlreadyEnrolled   = errors.New("inscription already exists")
+	ErrAlreadyWaiting    = errors.New("user is already on the waitlist")
+	ErrNotFound          = errors.New("inscription not found")
+	ErrInvalidStatus     = errors.New("invalid inscription status")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// Status estado de una inscripción en su ciclo de vida
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // Reservó el cupo pero todavía no comenzó el curso
+	StatusActive    Status = "active"    // Cursando
+	StatusCompleted Status = "completed" // Terminó el curso
+	StatusWithdrawn Status = "withdrawn" // Se retiró del curso
+	StatusExpired   Status = "expired"   // Venció sin completarse
+)
+
+// Transiciones válidas desde cada estado. Los estados sin transiciones son finales.
+var transitions = map[Status][]Status{
+	StatusPending: {StatusActive, StatusWithdrawn, StatusExpired},
+	StatusActive:  {StatusCompleted, StatusWithdrawn, StatusExpired},
+}
+
+// ParseStatus valida un estado recibido desde la API
+func ParseStatus(value string) (Status, error) {
+	status := Status(strings.ToLower(strings.TrimSpace(value)))
+	switch status {
+	case StatusPending, StatusActive, StatusCompleted, StatusWithdrawn, StatusExpired:
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidStatus, value)
+}
+
+// HoldsSeat indica si una inscripción en este estado ocupa un cupo del curso
+func (s Status) HoldsSeat() bool {
+	return s == StatusPending || s == StatusActive
+}
+
+// CanTransition indica si una inscripción puede pasar del estado s al estado to
+func (s Status) CanTransition(to Status) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Inscription struct {
-	ID       uint `json:"id"`
-	UserID   uint `json:"user_id"`
-	CourseID uint `json:"course_id"`
+	ID             uint       `json:"id"`
+	UserID         uint       `json:"user_id"`
+	CourseID       uint       `json:"course_id"`
+	Status         Status     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	WithdrawnAt    *time.Time `json:"withdrawn_at,omitempty"`
+	WithdrawReason string     `json:"withdraw_reason,omitempty"`
+}
+
+// StatusChange resultado de cambiar el estado de una inscripción. Al retirarse de
+// un curso sin tener cupo, Waitlist es el lugar liberado en la lista de espera y
+// no hay inscripción.
+type StatusChange struct {
+	Inscription *Inscription
+	Waitlist    *WaitlistEntry
+	// Estudiantes de la lista de espera que ocuparon el cupo liberado
+	Promoted []Inscription
+}
+
+// WaitlistEntry lugar de un estudiante en la lista de espera de un curso lleno.
+// Position empieza en 1 y corresponde al próximo estudiante en ser promovido.
+type WaitlistEntry struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	CourseID  uint      `json:"course_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Enrollment resultado de una solicitud de inscripción: la inscripción creada o,
+// si el curso está lleno, el lugar asignado en la lista de espera
+type Enrollment struct {
+	Inscription *Inscription
+	Waitlist    *WaitlistEntry
+	// Estudiantes de la lista de espera inscriptos en la misma transacción, porque
+	// la capacidad del curso había aumentado
+	Promoted []Inscription
 }
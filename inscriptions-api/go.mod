@@ -2,7 +2,11 @@ module inscriptions-api
 
 go 1.22.1
 
-require gorm.io/driver/mysql v1.5.7
+require (
+	events v0.0.0
+	github.com/streadway/amqp v1.1.0
+	gorm.io/driver/mysql v1.5.7
+)
 
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
@@ -40,3 +44,5 @@ require (
 	golang.org/x/text v0.15.0 // indirect
 	gorm.io/gorm v1.25.12 
 )
+
+replace events => ../events
@@ -2,15 +2,22 @@ package repositories
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"events"
 	"fmt"
+	"log"
 	"os"
+	"time"
 
 	dao "inscriptions-api/DAOs/inscriptions"
+	outboxDAO "inscriptions-api/DAOs/outbox"
 	domain "inscriptions-api/domain/inscriptions"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Función auxiliar para obtener variables de entorno con valores predeterminados
@@ -34,17 +41,75 @@ func Connect() (*gorm.DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=30s",
 		dbUser, dbPassword, dbHost, dbPort, dbName)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// TranslateError convierte las violaciones del índice único en gorm.ErrDuplicatedKey
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to MySQL: %v", err)
 	}
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate crea o actualiza las tablas del servicio
+func Migrate(db *gorm.DB) error {
+	if err := dedupeInscriptions(db); err != nil {
+		return fmt.Errorf("error migrating database: %v", err)
+	}
 
-	err = db.AutoMigrate(&dao.InscriptionModel{})
+	err := db.AutoMigrate(&dao.InscriptionModel{}, &dao.CourseSeatsModel{}, &dao.WaitlistModel{}, &outboxDAO.EventModel{})
 	if err != nil {
-		return nil, fmt.Errorf("error migrating database: %v", err)
+		return fmt.Errorf("error migrating database: %v", err)
 	}
 
-	return db, nil
+	// Las inscripciones anteriores a los estados no registraban estado ni fechas. Solo
+	// ellas tienen created_at en NULL, así que cada fila se completa una única vez y
+	// en una sola sentencia: si el servicio se detiene a mitad de la migración, el
+	// próximo arranque completa las que falten.
+	err = db.Exec(`UPDATE inscription_models
+		SET status = IF(withdrawn_at IS NULL, status, 'withdrawn'),
+			created_at = COALESCE(withdrawn_at, NOW()), updated_at = COALESCE(withdrawn_at, NOW())
+		WHERE created_at IS NULL`).Error
+	if err != nil {
+		return fmt.Errorf("error migrating database: %v", err)
+	}
+	return nil
+}
+
+// dedupeInscriptions elimina las inscripciones repetidas antes de crear el índice
+// único idx_user_course_active, que AutoMigrate no podría crear con duplicados.
+// Las filas anteriores a los estados solo tenían usuario y curso, así que se
+// conserva la primera de cada par. Si ya tienen estado no se puede elegir cuál
+// conservar y la migración falla indicando cuántos pares hay que resolver.
+func dedupeInscriptions(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&dao.InscriptionModel{}) || migrator.HasIndex(&dao.InscriptionModel{}, "idx_user_course_active") {
+		return nil
+	}
+
+	if migrator.HasColumn(&dao.InscriptionModel{}, "Active") {
+		var duplicated int64
+		err := db.Raw(`SELECT COUNT(*) FROM (SELECT user_id, course_id FROM inscription_models
+			WHERE active GROUP BY user_id, course_id HAVING COUNT(*) > 1) AS duplicated`).Scan(&duplicated).Error
+		if err != nil {
+			return err
+		}
+		if duplicated > 0 {
+			return fmt.Errorf("%d users have more than one active inscription in the same course, withdraw the extra ones before creating idx_user_course_active", duplicated)
+		}
+		return nil
+	}
+
+	result := db.Exec(`DELETE i FROM inscription_models i
+		JOIN inscription_models kept ON kept.user_id = i.user_id AND kept.course_id = i.course_id AND kept.id < i.id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Se eliminaron %d inscripciones repetidas antes de crear el índice único", result.RowsAffected)
+	}
+	return nil
 }
 
 type InscriptionRepository struct {
@@ -55,67 +120,585 @@ func NewInscriptionRepository(dao *dao.InscriptionDAO) *InscriptionRepository {
 	return &InscriptionRepository{dao: dao}
 }
 
-func (r *InscriptionRepository) CreateInscription(ctx context.Context, userID, courseID uint) (*domain.Inscription, error) {
-	var inscription dao.InscriptionModel
-	if err := r.dao.DB().WithContext(ctx).Where("user_id = ? AND course_id = ?", userID, courseID).
-		First(&inscription).Error; err == nil {
-		return nil, errors.New("inscription already exists")
-	} else if err != gorm.ErrRecordNotFound {
+// CreateInscription inscribe al usuario en una transacción que bloquea el contador
+// de cupos del curso, así las inscripciones concurrentes a un mismo curso se
+// procesan de a una y nunca superan la capacidad. Si el curso está lleno el
+// usuario queda al final de la lista de espera. Los índices únicos sobre
+// (user_id, course_id) rechazan las inscripciones y esperas duplicadas. Cada
+// inscripción registra su evento en el outbox dentro de la misma transacción.
+// La capacidad solo se aplica si version no es anterior a la ya guardada.
+func (r *InscriptionRepository) CreateInscription(ctx context.Context, userID, courseID uint, capacity int, version int64, status domain.Status) (domain.Enrollment, error) {
+	var enrollment domain.Enrollment
+	err := r.dao.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// La primera solicitud del curso crea su contador. INSERT IGNORE solo bloquea
+		// la fila del contador: leer las inscripciones acá tomaría locks compartidos
+		// sobre ellas que, con solicitudes concurrentes, terminan en deadlock
+		created := tx.Exec(`INSERT IGNORE INTO course_seats (course_id, capacity, taken, course_version) VALUES (?, ?, 0, ?)`, courseID, capacity, version)
+		if created.Error != nil {
+			return created.Error
+		}
+
+		seats, err := lockSeats(tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		// Con la fila bloqueada, el contador nuevo se completa con las inscripciones
+		// anteriores a él y se actualiza la capacidad, que puede haber cambiado en la
+		// API de cursos
+		updates := map[string]interface{}{}
+		if created.RowsAffected > 0 {
+			var taken int64
+			if err := tx.Model(&dao.InscriptionModel{}).Where("course_id = ? AND active", courseID).Count(&taken).Error; err != nil {
+				return err
+			}
+			seats.Taken = int(taken)
+			updates["taken"] = seats.Taken
+		}
+		for column, value := range capacityUpdates(&seats, capacity, version) {
+			updates[column] = value
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&seats).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		// Si la capacidad aumentó, los cupos nuevos son primero para la lista de espera
+		enrollment.Promoted, err = promote(tx, &seats)
+		if err != nil {
+			return err
+		}
+		for i, promoted := range enrollment.Promoted {
+			if promoted.UserID == userID {
+				enrollment.Inscription = &enrollment.Promoted[i]
+				return nil
+			}
+		}
+
+		if seats.Taken >= seats.Capacity {
+			enrollment.Waitlist, err = joinWaitlist(tx, userID, courseID)
+			return err
+		}
+
+		newInscription := dao.InscriptionModel{UserID: userID, CourseID: courseID, Status: string(status)}
+		if err := tx.Create(&newInscription).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return domain.ErrAlreadyEnrolled
+			}
+			return err
+		}
+		seats.Taken++
+		if err := tx.Model(&seats).Update("taken", seats.Taken).Error; err != nil {
+			return err
+		}
+		if err := recordEvent(tx, events.InscriptionCreated, newInscription, &seats); err != nil {
+			return err
+		}
+		inscription := mapModelToDomain(newInscription)
+		enrollment.Inscription = &inscription
+		return nil
+	})
+	if err != nil {
+		return domain.Enrollment{}, err
+	}
+
+	return enrollment, nil
+}
+
+// UpdateCapacity actualiza la capacidad del curso y promueve a la lista de espera
+// a los cupos que se hayan liberado. Devuelve las inscripciones creadas. Un
+// snapshot con version anterior a la guardada no cambia nada.
+func (r *InscriptionRepository) UpdateCapacity(ctx context.Context, courseID uint, capacity int, version int64) ([]domain.Inscription, error) {
+	var promoted []domain.Inscription
+	err := r.dao.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seats, err := lockSeatsIfAny(tx, courseID)
+		if err != nil {
+			return err
+		}
+		// Sin contador el curso nunca tuvo solicitudes, no hay nadie esperando
+		if seats == nil {
+			return nil
+		}
+
+		updates := capacityUpdates(seats, capacity, version)
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(seats).Updates(updates).Error; err != nil {
+			return err
+		}
+		promoted, err = promote(tx, seats)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return promoted, nil
+}
+
+// UpdateStatus cambia el estado de la inscripción si la transición es válida. Si la
+// inscripción deja de ocupar un cupo, este pasa al siguiente de la lista de espera.
+func (r *InscriptionRepository) UpdateStatus(ctx context.Context, id uint, status domain.Status) (domain.StatusChange, error) {
+	return r.changeStatus(ctx, id, status, "")
+}
+
+// WithdrawInscription marca la inscripción como retirada, libera su cupo y lo
+// asigna al siguiente de la lista de espera. La inscripción se conserva para los reportes.
+func (r *InscriptionRepository) WithdrawInscription(ctx context.Context, id uint, reason string) (domain.StatusChange, error) {
+	return r.changeStatus(ctx, id, domain.StatusWithdrawn, reason)
+}
+
+// changeStatus bloquea el contador de cupos del curso antes de leer la inscripción:
+// en REPEATABLE READ la primera lectura fija la foto de la transacción, y leerla
+// antes del bloqueo ocultaría las promociones que hizo otra transacción mientras
+// tanto.
+func (r *InscriptionRepository) changeStatus(ctx context.Context, id uint, status domain.Status, reason string) (domain.StatusChange, error) {
+	db := r.dao.DB().WithContext(ctx)
+
+	// El curso de una inscripción no cambia, se puede leer fuera de la transacción
+	var course dao.InscriptionModel
+	err := db.Select("course_id").First(&course, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.StatusChange{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return domain.StatusChange{}, err
+	}
+
+	var change domain.StatusChange
+	err = db.Transaction(func(tx *gorm.DB) error {
+		seats, err := lockSeatsIfAny(tx, course.CourseID)
+		if err != nil {
+			return err
+		}
+
+		var inscription dao.InscriptionModel
+		err = tx.First(&inscription, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		change, err = transition(tx, inscription, seats, status, reason)
+		return err
+	})
+	if err != nil {
+		return domain.StatusChange{}, err
+	}
+	return change, nil
+}
+
+// WithdrawFromCourse retira al usuario del curso. Si todavía estaba en la lista de
+// espera solo se elimina su lugar en ella.
+func (r *InscriptionRepository) WithdrawFromCourse(ctx context.Context, userID, courseID uint, reason string) (domain.StatusChange, error) {
+	var change domain.StatusChange
+	err := r.dao.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// La lista de espera solo se modifica con el contador bloqueado, igual que en promote
+		seats, err := lockSeatsIfAny(tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		var inscriptions []dao.InscriptionModel
+		if err := tx.Where("user_id = ? AND course_id = ? AND active", userID, courseID).
+			Limit(1).Find(&inscriptions).Error; err != nil {
+			return err
+		}
+		if len(inscriptions) > 0 {
+			change, err = transition(tx, inscriptions[0], seats, domain.StatusWithdrawn, reason)
+			return err
+		}
+
+		var waiting []dao.WaitlistModel
+		if err := tx.Where("user_id = ? AND course_id = ?", userID, courseID).Limit(1).Find(&waiting).Error; err != nil {
+			return err
+		}
+		if len(waiting) == 0 {
+			return domain.ErrNotFound
+		}
+		if err := tx.Delete(&waiting[0]).Error; err != nil {
+			return err
+		}
+		entry := mapWaitlistToDomain(waiting[0], 0)
+		change.Waitlist = &entry
+		return nil
+	})
+	if err != nil {
+		return domain.StatusChange{}, err
+	}
+	return change, nil
+}
+
+// transition cambia el estado de una inscripción validando la transición. Si deja
+// de ocupar un cupo lo libera y promueve al siguiente de la lista de espera. seats
+// es el contador del curso ya bloqueado, nil si el curso no tiene contador.
+func transition(tx *gorm.DB, inscription dao.InscriptionModel, seats *dao.CourseSeatsModel, to domain.Status, reason string) (domain.StatusChange, error) {
+	from := domain.Status(inscription.Status)
+	if !from.CanTransition(to) {
+		return domain.StatusChange{}, fmt.Errorf("%w: %s to %s", domain.ErrInvalidTransition, from, to)
+	}
+	releases := from.HoldsSeat() && !to.HoldsSeat()
+	seated := releases && seats != nil
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": string(to), "updated_at": now}
+	inscription.Status = string(to)
+	inscription.UpdatedAt = now
+	if releases {
+		updates["active"] = nil
+		inscription.Active = nil
+	}
+	switch to {
+	case domain.StatusCompleted:
+		updates["completed_at"] = now
+		inscription.CompletedAt = &now
+	case domain.StatusWithdrawn:
+		updates["withdrawn_at"] = now
+		updates["withdraw_reason"] = reason
+		inscription.WithdrawnAt = &now
+		inscription.WithdrawReason = reason
+	}
+
+	// La condición sobre el estado anterior evita aplicar dos cambios concurrentes a
+	// la misma inscripción, por ejemplo liberar dos veces su cupo
+	result := tx.Model(&dao.InscriptionModel{}).
+		Where("id = ? AND status = ?", inscription.ID, string(from)).
+		Updates(updates)
+	if result.Error != nil {
+		return domain.StatusChange{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.StatusChange{}, fmt.Errorf("%w: the inscription is no longer %s", domain.ErrInvalidTransition, from)
+	}
 
-	newInscription := dao.InscriptionModel{UserID: userID, CourseID: courseID}
-	if err := r.dao.DB().WithContext(ctx).Create(&newInscription).Error; err != nil {
+	// Las inscripciones anteriores al contador de cupos no tienen fila; el contador
+	// se crea con la próxima inscripción al curso, ya sin contar esta
+	var remaining *dao.CourseSeatsModel
+	if seated {
+		seats.Taken--
+		if err := tx.Model(seats).Update("taken", seats.Taken).Error; err != nil {
+			return domain.StatusChange{}, err
+		}
+		remaining = seats
+	}
+	if eventType, ok := statusEvents[to]; ok {
+		if err := recordEvent(tx, eventType, inscription, remaining); err != nil {
+			return domain.StatusChange{}, err
+		}
+	}
+
+	changed := mapModelToDomain(inscription)
+	change := domain.StatusChange{Inscription: &changed}
+	if !seated {
+		return change, nil
+	}
+	var err error
+	change.Promoted, err = promote(tx, seats)
+	if err != nil {
+		return domain.StatusChange{}, err
+	}
+	return change, nil
+}
+
+// ClearWaitlist elimina la lista de espera de un curso, con el contador de cupos
+// bloqueado para no competir con una promoción en curso
+func (r *InscriptionRepository) ClearWaitlist(ctx context.Context, courseID uint) error {
+	return r.dao.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockSeatsIfAny(tx, courseID); err != nil {
+			return err
+		}
+		return tx.Where("course_id = ?", courseID).Delete(&dao.WaitlistModel{}).Error
+	})
+}
+
+// GetWaitlistByCourse devuelve la lista de espera del curso en orden de promoción
+func (r *InscriptionRepository) GetWaitlistByCourse(ctx context.Context, courseID uint) ([]domain.WaitlistEntry, error) {
+	var models []dao.WaitlistModel
+	if err := r.dao.DB().WithContext(ctx).Where("course_id = ?", courseID).Order("id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.WaitlistEntry, len(models))
+	for i, model := range models {
+		entries[i] = mapWaitlistToDomain(model, i+1)
+	}
+	return entries, nil
+}
+
+// GetWaitlistByUser devuelve los cursos en los que el usuario está esperando, con
+// su posición en cada lista
+func (r *InscriptionRepository) GetWaitlistByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error) {
+	var rows []struct {
+		dao.WaitlistModel
+		Position int
+	}
+	err := r.dao.DB().WithContext(ctx).Table("course_waitlist AS w").
+		Select(`w.*, (SELECT COUNT(*) FROM course_waitlist o
+			WHERE o.course_id = w.course_id AND o.id <= w.id) AS position`).
+		Where("w.user_id = ?", userID).
+		Order("w.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.WaitlistEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = mapWaitlistToDomain(row.WaitlistModel, row.Position)
+	}
+	return entries, nil
+}
+
+// lockSeats lee el contador de cupos del curso bloqueando su fila hasta el final de la transacción
+func lockSeats(tx *gorm.DB, courseID uint) (dao.CourseSeatsModel, error) {
+	var seats dao.CourseSeatsModel
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&seats, "course_id = ?", courseID).Error
+	return seats, err
+}
+
+// capacityUpdates aplica al contador la capacidad del snapshot del curso con la
+// versión indicada y devuelve las columnas que cambiaron. Los snapshots anteriores
+// al último aplicado se descartan: sin eso, un evento desordenado restauraría una
+// capacidad vieja. Los mensajes sin versión solo se aplican mientras el contador
+// tampoco tenga una.
+func capacityUpdates(seats *dao.CourseSeatsModel, capacity int, version int64) map[string]interface{} {
+	updates := map[string]interface{}{}
+	if version < seats.CourseVersion {
+		log.Printf("Ignoring capacity %d of course %d: version %d is older than %d", capacity, seats.CourseID, version, seats.CourseVersion)
+		return updates
+	}
+	if seats.Capacity != capacity {
+		seats.Capacity = capacity
+		updates["capacity"] = capacity
+	}
+	if version > seats.CourseVersion {
+		seats.CourseVersion = version
+		updates["course_version"] = version
+	}
+	return updates
+}
+
+// lockSeatsIfAny bloquea el contador de cupos del curso como lockSeats. Devuelve
+// nil si el curso no tiene contador, como los que solo tienen inscripciones
+// anteriores a él.
+func lockSeatsIfAny(tx *gorm.DB, courseID uint) (*dao.CourseSeatsModel, error) {
+	seats, err := lockSeats(tx, courseID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &seats, nil
+}
+
+// promote inscribe en orden de llegada a los estudiantes de la lista de espera
+// mientras haya cupos libres. Debe llamarse con la fila de cupos bloqueada.
+func promote(tx *gorm.DB, seats *dao.CourseSeatsModel) ([]domain.Inscription, error) {
+	var promoted []domain.Inscription
+	for seats.Taken < seats.Capacity {
+		// La lectura bloqueante ve la lista confirmada y no la foto de la transacción,
+		// que puede incluir lugares ya promovidos por otra
+		var next []dao.WaitlistModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("course_id = ?", seats.CourseID).Order("id").Limit(1).Find(&next).Error; err != nil {
+			return nil, err
+		}
+		if len(next) == 0 {
+			break
+		}
+		deleted := tx.Delete(&next[0])
+		if deleted.Error != nil {
+			return nil, deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			return nil, fmt.Errorf("waitlist entry %d was already removed", next[0].ID)
+		}
+
+		inscription := dao.InscriptionModel{UserID: next[0].UserID, CourseID: next[0].CourseID, Status: string(domain.StatusActive)}
+		if err := tx.Create(&inscription).Error; err != nil {
+			// Si ya estaba inscripto solo se descarta su lugar en la lista
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				continue
+			}
+			return nil, err
+		}
+		seats.Taken++
+		if err := recordEvent(tx, events.InscriptionPromoted, inscription, seats); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, mapModelToDomain(inscription))
+	}
+
+	if len(promoted) == 0 {
+		return nil, nil
+	}
+	if err := tx.Model(seats).Update("taken", seats.Taken).Error; err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// statusEvents tipo de evento publicado al pasar a cada estado. Pasar de pendiente
+// a activa no cambia los cupos del curso y no publica ningún evento.
+var statusEvents = map[domain.Status]events.InscriptionEventType{
+	domain.StatusCompleted: events.InscriptionCompleted,
+	domain.StatusWithdrawn: events.InscriptionWithdrawn,
+	domain.StatusExpired:   events.InscriptionExpired,
+}
+
+// recordEvent registra en el outbox el evento del cambio de la inscripción, con
+// los cupos que le quedan al curso según seats ya actualizado (nil si el curso no
+// tiene contador) y una nueva versión del contador. Debe llamarse dentro de la
+// transacción del cambio, con el contador bloqueado, para que ambos se confirmen
+// juntos y las versiones sigan el orden de los cambios.
+func recordEvent(tx *gorm.DB, eventType events.InscriptionEventType, inscription dao.InscriptionModel, seats *dao.CourseSeatsModel) error {
+	event := outboxDAO.EventModel{
+		EventID:       newEventID(),
+		Type:          string(eventType),
+		InscriptionID: inscription.ID,
+		UserID:        inscription.UserID,
+		CourseID:      inscription.CourseID,
+		Reason:        inscription.WithdrawReason,
+		Status:        outboxDAO.StatusPending,
+	}
+	if seats != nil {
+		remaining := seats.Capacity - seats.Taken
+		if remaining < 0 {
+			remaining = 0
+		}
+		seats.Version++
+		if err := tx.Model(seats).Update("version", seats.Version).Error; err != nil {
+			return err
+		}
+		event.SeatsRemaining = &remaining
+		event.SeatsVersion = seats.Version
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// newEventID genera un identificador aleatorio para un evento
+func newEventID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// joinWaitlist agrega al usuario al final de la lista de espera del curso
+func joinWaitlist(tx *gorm.DB, userID, courseID uint) (*domain.WaitlistEntry, error) {
+	var enrolled int64
+	if err := tx.Model(&dao.InscriptionModel{}).
+		Where("user_id = ? AND course_id = ? AND active", userID, courseID).
+		Count(&enrolled).Error; err != nil {
 		return nil, err
 	}
+	if enrolled > 0 {
+		return nil, domain.ErrAlreadyEnrolled
+	}
 
-	return &domain.Inscription{
-		ID:       newInscription.ID,
-		UserID:   newInscription.UserID,
-		CourseID: newInscription.CourseID,
-	}, nil
+	entry := dao.WaitlistModel{UserID: userID, CourseID: courseID}
+	if err := tx.Create(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, domain.ErrAlreadyWaiting
+		}
+		return nil, err
+	}
+
+	var position int64
+	if err := tx.Model(&dao.WaitlistModel{}).
+		Where("course_id = ? AND id <= ?", courseID, entry.ID).
+		Count(&position).Error; err != nil {
+		return nil, err
+	}
+	waiting := mapWaitlistToDomain(entry, int(position))
+	return &waiting, nil
 }
 
-func (r *InscriptionRepository) GetInscriptions(ctx context.Context) ([]domain.Inscription, error) {
+func (r *InscriptionRepository) GetInscriptions(ctx context.Context, statuses []domain.Status) ([]domain.Inscription, error) {
 	var inscriptionsModel []dao.InscriptionModel
-	if err := r.dao.DB().WithContext(ctx).Find(&inscriptionsModel).Error; err != nil {
+	if err := withStatuses(r.dao.DB().WithContext(ctx), statuses).Find(&inscriptionsModel).Error; err != nil {
 		return nil, err
 	}
 
 	return r.mapModelsToDomain(inscriptionsModel), nil
 }
 
-func (r *InscriptionRepository) GetInscriptionsByUser(ctx context.Context, userID uint) ([]domain.Inscription, error) {
+func (r *InscriptionRepository) GetInscriptionsByUser(ctx context.Context, userID uint, statuses []domain.Status) ([]domain.Inscription, error) {
 	var inscriptionsModel []dao.InscriptionModel
-	if err := r.dao.DB().WithContext(ctx).Where("user_id = ?", userID).Find(&inscriptionsModel).Error; err != nil {
+	query := r.dao.DB().WithContext(ctx).Where("user_id = ?", userID)
+	if err := withStatuses(query, statuses).Find(&inscriptionsModel).Error; err != nil {
 		return nil, err
 	}
 
 	return r.mapModelsToDomain(inscriptionsModel), nil
 }
 
+// withStatuses filtra la consulta por los estados indicados; sin estados no filtra
+func withStatuses(query *gorm.DB, statuses []domain.Status) *gorm.DB {
+	if len(statuses) == 0 {
+		return query
+	}
+	return query.Where("status IN ?", statuses)
+}
+
 func (r *InscriptionRepository) mapModelsToDomain(models []dao.InscriptionModel) []domain.Inscription {
 	inscriptions := make([]domain.Inscription, len(models))
 	for i, model := range models {
-		inscriptions[i] = domain.Inscription{
-			ID:       model.ID,
-			UserID:   model.UserID,
-			CourseID: model.CourseID,
-		}
+		inscriptions[i] = mapModelToDomain(model)
 	}
 	return inscriptions
 }
 
+func mapModelToDomain(model dao.InscriptionModel) domain.Inscription {
+	return domain.Inscription{
+		ID:             model.ID,
+		UserID:         model.UserID,
+		CourseID:       model.CourseID,
+		Status:         domain.Status(model.Status),
+		CreatedAt:      model.CreatedAt,
+		UpdatedAt:      model.UpdatedAt,
+		CompletedAt:    model.CompletedAt,
+		WithdrawnAt:    model.WithdrawnAt,
+		WithdrawReason: model.WithdrawReason,
+	}
+}
+
+func mapWaitlistToDomain(model dao.WaitlistModel, position int) domain.WaitlistEntry {
+	return domain.WaitlistEntry{
+		ID:        model.ID,
+		UserID:    model.UserID,
+		CourseID:  model.CourseID,
+		Position:  position,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
 type Repository interface {
-	CreateInscription(ctx context.Context, userID, courseID uint) (*domain.Inscription, error)
-	GetInscriptions(ctx context.Context) ([]domain.Inscription, error)
-	GetInscriptionsByUser(ctx context.Context, userID uint) ([]domain.Inscription, error)
-	GetInscriptionsByCourse(ctx context.Context, courseID uint) ([]domain.Inscription, error)
+	CreateInscription(ctx context.Context, userID, courseID uint, capacity int, version int64, status domain.Status) (domain.Enrollment, error)
+	UpdateCapacity(ctx context.Context, courseID uint, capacity int, version int64) ([]domain.Inscription, error)
+	UpdateStatus(ctx context.Context, id uint, status domain.Status) (domain.StatusChange, error)
+	WithdrawInscription(ctx context.Context, id uint, reason string) (domain.StatusChange, error)
+	WithdrawFromCourse(ctx context.Context, userID, courseID uint, reason string) (domain.StatusChange, error)
+	ClearWaitlist(ctx context.Context, courseID uint) error
+	GetWaitlistByCourse(ctx context.Context, courseID uint) ([]domain.WaitlistEntry, error)
+	GetWaitlistByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error)
+	GetInscriptions(ctx context.Context, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByUser(ctx context.Context, userID uint, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByCourse(ctx context.Context, courseID uint, statuses []domain.Status) ([]domain.Inscription, error)
 }
 
-func (r *InscriptionRepository) GetInscriptionsByCourse(ctx context.Context, courseID uint) ([]domain.Inscription, error) {
+func (r *InscriptionRepository) GetInscriptionsByCourse(ctx context.Context, courseID uint, statuses []domain.Status) ([]domain.Inscription, error) {
 	var inscriptionsModel []dao.InscriptionModel
-	if err := r.dao.DB().WithContext(ctx).Where("course_id = ?", courseID).Find(&inscriptionsModel).Error; err != nil {
+	query := r.dao.DB().WithContext(ctx).Where("course_id = ?", courseID)
+	if err := withStatuses(query, statuses).Find(&inscriptionsModel).Error; err != nil {
 		return nil, err
 	}
 
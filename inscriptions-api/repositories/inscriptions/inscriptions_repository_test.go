@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"events"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	dao "inscriptions-api/DAOs/inscriptions"
+	outboxDAO "inscriptions-api/DAOs/outbox"
+	domain "inscriptions-api/domain/inscriptions"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Las pruebas necesitan un MySQL real, por ejemplo:
+//
+//	MYSQL_TEST_DSN="root:rootpassword@tcp(localhost:3306)/inscriptions_test?parseTime=True" go test ./...
+func testRepository(t *testing.T) (*InscriptionRepository, *gorm.DB) {
+	t.Helper()
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN no está definida")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("error connecting to MySQL: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return NewInscriptionRepository(dao.NewInscriptionDAO(db)), db
+}
+
+// testCourse devuelve un curso sin inscripciones previas
+func testCourse(t *testing.T, db *gorm.DB) uint {
+	t.Helper()
+	courseID := uint(time.Now().UnixNano() % 1_000_000_000)
+	t.Cleanup(func() {
+		db.Where("course_id = ?", courseID).Delete(&dao.InscriptionModel{})
+		db.Where("course_id = ?", courseID).Delete(&dao.WaitlistModel{})
+		db.Where("course_id = ?", courseID).Delete(&dao.CourseSeatsModel{})
+		db.Where("course_id = ?", courseID).Delete(&outboxDAO.EventModel{})
+	})
+	return courseID
+}
+
+func TestCreateInscriptionConcurrentNeverExceedsCapacity(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	const capacity, students = 5, 30
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		enrolled int
+		waiting  int
+		failures []error
+	)
+	for user := 1; user <= students; user++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			enrollment, err := repository.CreateInscription(context.Background(), userID, courseID, capacity, 0, domain.StatusActive)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				failures = append(failures, err)
+			case enrollment.Inscription != nil:
+				enrolled++
+			case enrollment.Waitlist != nil:
+				waiting++
+			}
+		}(uint(user))
+	}
+	wg.Wait()
+
+	// Ninguna solicitud puede terminar en deadlock ni en otro error
+	for _, err := range failures {
+		t.Errorf("CreateInscription: %v", err)
+	}
+	if enrolled != capacity || waiting != students-capacity {
+		t.Errorf("inscriptos = %d, en espera = %d, want %d y %d", enrolled, waiting, capacity, students-capacity)
+	}
+
+	var seats dao.CourseSeatsModel
+	if err := db.First(&seats, "course_id = ?", courseID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var active int64
+	db.Model(&dao.InscriptionModel{}).Where("course_id = ? AND active", courseID).Count(&active)
+	if seats.Taken != capacity || active != capacity {
+		t.Errorf("taken = %d, inscripciones activas = %d, want %d", seats.Taken, active, capacity)
+	}
+}
+
+func TestCreateInscriptionConcurrentSameUser(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	const attempts = 10
+
+	var wg sync.WaitGroup
+	errs := make([]error, attempts)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = repository.CreateInscription(context.Background(), 1, courseID, 5, 0, domain.StatusActive)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, domain.ErrAlreadyEnrolled):
+			t.Errorf("CreateInscription: %v, want %v", err, domain.ErrAlreadyEnrolled)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("inscripciones creadas = %d, want 1", succeeded)
+	}
+}
+
+// Dos retiros simultáneos de un curso lleno promueven a dos estudiantes distintos
+// de la lista de espera, sin que uno repita la promoción del otro
+func TestWithdrawInscriptionConcurrentPromotesEachOnce(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	const capacity, students = 2, 4
+
+	var seated []uint
+	for user := uint(1); user <= students; user++ {
+		enrollment, err := repository.CreateInscription(context.Background(), user, courseID, capacity, 0, domain.StatusActive)
+		if err != nil {
+			t.Fatalf("CreateInscription: %v", err)
+		}
+		if enrollment.Inscription != nil {
+			seated = append(seated, enrollment.Inscription.ID)
+		}
+	}
+	if len(seated) != capacity {
+		t.Fatalf("inscriptos = %d, want %d", len(seated), capacity)
+	}
+
+	// El límite de tiempo corta la prueba si un retiro queda en un ciclo infinito
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	changes := make([]domain.StatusChange, len(seated))
+	errs := make([]error, len(seated))
+	for i, id := range seated {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			changes[i], errs[i] = repository.WithdrawInscription(ctx, id, "")
+		}(i, id)
+	}
+	wg.Wait()
+
+	promoted := map[uint]bool{}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("WithdrawInscription: %v", err)
+		}
+		for _, inscription := range changes[i].Promoted {
+			promoted[inscription.UserID] = true
+		}
+	}
+	if len(promoted) != students-capacity {
+		t.Errorf("promovidos = %v, want %d estudiantes distintos", promoted, students-capacity)
+	}
+
+	var seats dao.CourseSeatsModel
+	if err := db.First(&seats, "course_id = ?", courseID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var waiting int64
+	db.Model(&dao.WaitlistModel{}).Where("course_id = ?", courseID).Count(&waiting)
+	if seats.Taken != capacity || waiting != 0 {
+		t.Errorf("taken = %d, en espera = %d, want %d y 0", seats.Taken, waiting, capacity)
+	}
+}
+
+// Las inscripciones anteriores al contador de cupos se cuentan al crearlo
+func TestCreateInscriptionCountsExistingInscriptions(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	for user := uint(1); user <= 2; user++ {
+		if err := db.Create(&dao.InscriptionModel{UserID: user, CourseID: courseID, Status: string(domain.StatusActive)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enrollment, err := repository.CreateInscription(context.Background(), 3, courseID, 3, 0, domain.StatusActive)
+	if err != nil || enrollment.Inscription == nil {
+		t.Fatalf("CreateInscription = %+v, %v, want una inscripción", enrollment, err)
+	}
+	enrollment, err = repository.CreateInscription(context.Background(), 4, courseID, 3, 0, domain.StatusActive)
+	if err != nil || enrollment.Waitlist == nil {
+		t.Fatalf("CreateInscription = %+v, %v, want lista de espera", enrollment, err)
+	}
+}
+
+// Cada evento informa los cupos que le quedan al curso después de su cambio, con
+// versiones crecientes del contador
+func TestEventsCarrySeatsRemaining(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	ctx := context.Background()
+
+	first, err := repository.CreateInscription(ctx, 1, courseID, 1, 0, domain.StatusActive)
+	if err != nil || first.Inscription == nil {
+		t.Fatalf("CreateInscription = %+v, %v, want una inscripción", first, err)
+	}
+	if _, err := repository.CreateInscription(ctx, 2, courseID, 1, 0, domain.StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	change, err := repository.WithdrawInscription(ctx, first.Inscription.ID, "cambio de horario")
+	if err != nil || len(change.Promoted) != 1 {
+		t.Fatalf("WithdrawInscription = %+v, %v, want una promoción", change, err)
+	}
+
+	var recorded []outboxDAO.EventModel
+	if err := db.Where("course_id = ?", courseID).Order("id").Find(&recorded).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		eventType string
+		seats     int
+		version   int64
+	}{
+		{string(events.InscriptionCreated), 0, 1},
+		{string(events.InscriptionWithdrawn), 1, 2},
+		{string(events.InscriptionPromoted), 0, 3},
+	}
+	if len(recorded) != len(want) {
+		t.Fatalf("eventos = %d, want %d", len(recorded), len(want))
+	}
+	for i, event := range recorded {
+		if event.Type != want[i].eventType || event.SeatsRemaining == nil || *event.SeatsRemaining != want[i].seats || event.SeatsVersion != want[i].version {
+			t.Errorf("evento %d = %s con cupos %v (versión %d), want %s con %d (versión %d)",
+				i, event.Type, event.SeatsRemaining, event.SeatsVersion, want[i].eventType, want[i].seats, want[i].version)
+		}
+	}
+}
+
+// Un snapshot del curso que llega después de uno más nuevo no restaura su
+// capacidad ni promueve a la lista de espera
+func TestUpdateCapacityIgnoresOutdatedSnapshots(t *testing.T) {
+	repository, db := testRepository(t)
+	courseID := testCourse(t, db)
+	ctx := context.Background()
+
+	for user := uint(1); user <= 2; user++ {
+		if _, err := repository.CreateInscription(ctx, user, courseID, 1, 1, domain.StatusActive); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repository.UpdateCapacity(ctx, courseID, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err := repository.UpdateCapacity(ctx, courseID, 2, 2)
+	if err != nil || len(promoted) != 0 {
+		t.Fatalf("UpdateCapacity = %+v, %v, want sin promociones", promoted, err)
+	}
+
+	var seats dao.CourseSeatsModel
+	if err := db.First(&seats, "course_id = ?", courseID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if seats.Capacity != 1 || seats.CourseVersion != 3 {
+		t.Errorf("cupos = capacidad %d (versión %d), want capacidad 1 (versión 3)", seats.Capacity, seats.CourseVersion)
+	}
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	dao "inscriptions-api/DAOs/outbox"
+
+	"gorm.io/gorm"
+)
+
+// Largo máximo del último error guardado, el de la columna last_error
+const maxErrorLength = 1024
+
+// OutboxRepository repositorio MySQL del outbox de eventos de inscripciones
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimNext reserva durante lease el evento pendiente más antiguo que no esté
+// reservado por otra instancia. La reserva se toma con un único UPDATE ... LIMIT 1,
+// así dos instancias nunca reservan el mismo evento. Devuelve nil si no hay
+// eventos para publicar.
+func (r *OutboxRepository) ClaimNext(ctx context.Context, lease time.Duration) (*dao.EventModel, error) {
+	now := time.Now()
+	token := newClaimToken()
+	result := r.db.WithContext(ctx).Exec(`UPDATE inscription_outbox SET claimed_until = ?, claim_token = ?
+		WHERE status = ? AND (claimed_until IS NULL OR claimed_until < ?)
+		ORDER BY id LIMIT 1`, now.Add(lease), token, dao.StatusPending, now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim pending event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var event dao.EventModel
+	if err := r.db.WithContext(ctx).Where("claim_token = ?", token).First(&event).Error; err != nil {
+		return nil, fmt.Errorf("failed to read claimed event: %w", err)
+	}
+	return &event, nil
+}
+
+// MarkSent marca el evento como publicado
+func (r *OutboxRepository) MarkSent(ctx context.Context, event dao.EventModel) error {
+	if err := r.db.WithContext(ctx).Model(&dao.EventModel{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+		"status":        dao.StatusSent,
+		"sent_at":       time.Now(),
+		"attempts":      gorm.Expr("attempts + 1"),
+		"claimed_until": nil,
+		"claim_token":   "",
+	}).Error; err != nil {
+		return fmt.Errorf("failed to mark event as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed registra un intento fallido de publicación. El evento sigue
+// pendiente, o pasa a fallido si dead indica que agotó sus intentos.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, event dao.EventModel, cause error, dead bool) error {
+	lastError := cause.Error()
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+	}
+	updates := map[string]interface{}{
+		"last_error":    lastError,
+		"attempts":      gorm.Expr("attempts + 1"),
+		"claimed_until": nil,
+		"claim_token":   "",
+	}
+	if dead {
+		updates["status"] = dao.StatusFailed
+	}
+	if err := r.db.WithContext(ctx).Model(&dao.EventModel{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record event failure: %w", err)
+	}
+	return nil
+}
+
+// Attempts devuelve los intentos de publicación fallidos del evento
+func (r *OutboxRepository) Attempts(event dao.EventModel) int {
+	return event.Attempts
+}
+
+// DeleteSent elimina los eventos publicados antes de before
+func (r *OutboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND sent_at < ?", dao.StatusSent, before).
+		Delete(&dao.EventModel{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete sent events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// newClaimToken genera el identificador de una reserva
+func newClaimToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -10,6 +10,11 @@ import (
 func MapRoutes(r *gin.Engine, ctrl *controller.Controller) {
 	r.POST("/inscriptions", ctrl.CreateInscription)
 	r.GET("/inscriptions", ctrl.GetInscriptions)
+	r.DELETE("/inscriptions/:id", ctrl.WithdrawInscription)
+	r.PATCH("/inscriptions/:id/status", ctrl.UpdateStatus)
+	r.DELETE("/users/:userID/courses/:courseID", ctrl.WithdrawFromCourse)
 	r.GET("/users/:userID/inscriptions", ctrl.GetInscriptionsByUser)
 	r.GET("/courses/:courseID/inscriptions", ctrl.GetInscriptionsByCourse)
+	r.GET("/users/:userID/waitlist", ctrl.GetWaitlistByUser)
+	r.GET("/courses/:courseID/waitlist", ctrl.GetWaitlistByCourse)
 }
@@ -2,17 +2,24 @@ package service
 
 import (
 	"context"
-	"errors"
+	"events"
 	"fmt"
 	"inscriptions-api/clients"
 	domain "inscriptions-api/domain/inscriptions"
 )
 
 type Repository interface {
-	CreateInscription(ctx context.Context, userID, courseID uint) (*domain.Inscription, error)
-	GetInscriptions(ctx context.Context) ([]domain.Inscription, error)
-	GetInscriptionsByUser(ctx context.Context, userID uint) ([]domain.Inscription, error)
-	GetInscriptionsByCourse(ctx context.Context, courseID uint) ([]domain.Inscription, error)
+	CreateInscription(ctx context.Context, userID, courseID uint, capacity int, version int64, status domain.Status) (domain.Enrollment, error)
+	UpdateCapacity(ctx context.Context, courseID uint, capacity int, version int64) ([]domain.Inscription, error)
+	UpdateStatus(ctx context.Context, id uint, status domain.Status) (domain.StatusChange, error)
+	WithdrawInscription(ctx context.Context, id uint, reason string) (domain.StatusChange, error)
+	WithdrawFromCourse(ctx context.Context, userID, courseID uint, reason string) (domain.StatusChange, error)
+	ClearWaitlist(ctx context.Context, courseID uint) error
+	GetWaitlistByCourse(ctx context.Context, courseID uint) ([]domain.WaitlistEntry, error)
+	GetWaitlistByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error)
+	GetInscriptions(ctx context.Context, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByUser(ctx context.Context, userID uint, statuses []domain.Status) ([]domain.Inscription, error)
+	GetInscriptionsByCourse(ctx context.Context, courseID uint, statuses []domain.Status) ([]domain.Inscription, error)
 }
 
 type Service struct {
@@ -24,51 +31,124 @@ func NewService(repository Repository, httpClient *clients.HTTPClient) *Service
 	return &Service{repository: repository, httpClient: httpClient}
 }
 
-func (s *Service) CreateInscription(ctx context.Context, userID, courseID uint) (*domain.Inscription, error) {
+// CreateInscription inscribe al usuario o, si el curso está lleno, lo agrega a la
+// lista de espera. El resultado indica cuál de las dos cosas ocurrió. La inscripción
+// comienza pendiente o activa; un estudiante promovido de la lista de espera
+// siempre queda activo.
+func (s *Service) CreateInscription(ctx context.Context, userID, courseID uint, status domain.Status) (domain.Enrollment, error) {
+	if !status.HoldsSeat() {
+		return domain.Enrollment{}, fmt.Errorf("%w: an inscription must start as %s or %s", domain.ErrInvalidStatus, domain.StatusPending, domain.StatusActive)
+	}
+
 	// Verificar si el usuario existe (usando la implementación temporal)
 	if err := s.httpClient.CheckUserExists(userID); err != nil {
-		return nil, fmt.Errorf("failed to verify user: %v", err)
+		return domain.Enrollment{}, fmt.Errorf("failed to verify user: %v", err)
 	}
 
 	// Verificar si el curso existe y obtener su capacidad
 	course, err := s.httpClient.GetCourseDetails(courseID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify course: %v", err)
+		return domain.Enrollment{}, fmt.Errorf("failed to verify course: %v", err)
+	}
+
+	// Crear la inscripción; el repositorio verifica los cupos de forma atómica
+	enrollment, err := s.repository.CreateInscription(ctx, userID, courseID, course.Capacity, course.Version, status)
+	if err != nil {
+		return domain.Enrollment{}, fmt.Errorf("failed to create inscription: %w", err)
 	}
 
-	// Obtener el número actual de inscripciones para el curso
-	currentInscriptions, err := s.repository.GetInscriptionsByCourse(ctx, courseID)
+	return enrollment, nil
+}
+
+// UpdateStatus cambia el estado de la inscripción. Si deja de ocupar un cupo, este
+// se asigna al siguiente de la lista de espera.
+func (s *Service) UpdateStatus(ctx context.Context, id uint, status domain.Status) (domain.StatusChange, error) {
+	change, err := s.repository.UpdateStatus(ctx, id, status)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current inscriptions: %v", err)
+		return domain.StatusChange{}, fmt.Errorf("failed to update inscription status: %w", err)
 	}
+	return change, nil
+}
 
-	// Verificar si hay cupos disponibles
-	if len(currentInscriptions) >= course.Capacity {
-		return nil, errors.New("course is at full capacity")
+// WithdrawInscription retira la inscripción y asigna el cupo liberado al siguiente
+// de la lista de espera
+func (s *Service) WithdrawInscription(ctx context.Context, id uint, reason string) (domain.StatusChange, error) {
+	withdrawal, err := s.repository.WithdrawInscription(ctx, id, reason)
+	if err != nil {
+		return domain.StatusChange{}, fmt.Errorf("failed to withdraw inscription: %w", err)
 	}
+	return withdrawal, nil
+}
 
-	// Crear la inscripción
-	inscription, err := s.repository.CreateInscription(ctx, userID, courseID)
+// WithdrawFromCourse retira al usuario del curso, o de su lista de espera si
+// todavía no tenía cupo
+func (s *Service) WithdrawFromCourse(ctx context.Context, userID, courseID uint, reason string) (domain.StatusChange, error) {
+	withdrawal, err := s.repository.WithdrawFromCourse(ctx, userID, courseID, reason)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create inscription: %v", err)
+		return domain.StatusChange{}, fmt.Errorf("failed to withdraw from course: %w", err)
+	}
+	return withdrawal, nil
+}
+
+// HandleCourseEvent mantiene la lista de espera al día con los cambios de los cursos:
+// si aumenta la capacidad promueve a los siguientes estudiantes y si el curso se
+// elimina descarta su lista de espera
+func (s *Service) HandleCourseEvent(event events.CourseEvent) error {
+	ctx := context.Background()
+	courseID := uint(event.CourseID)
+
+	switch event.Operation {
+	case events.OperationUpdate:
+		var capacity int
+		var version int64
+		if event.Course != nil {
+			capacity, version = event.Course.Capacity, event.Course.Version
+		} else {
+			// Los mensajes anteriores al snapshot no traen la capacidad
+			course, err := s.httpClient.GetCourseDetails(courseID)
+			if err != nil {
+				return fmt.Errorf("failed to get course %d: %w", courseID, err)
+			}
+			capacity, version = course.Capacity, course.Version
+		}
+
+		if _, err := s.repository.UpdateCapacity(ctx, courseID, capacity, version); err != nil {
+			return fmt.Errorf("failed to update capacity of course %d: %w", courseID, err)
+		}
+	case events.OperationDelete:
+		if err := s.repository.ClearWaitlist(ctx, courseID); err != nil {
+			return fmt.Errorf("failed to clear waitlist of course %d: %w", courseID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetWaitlistByCourse(ctx context.Context, courseID uint) ([]domain.WaitlistEntry, error) {
+	// Verificar si el curso existe
+	if err := s.httpClient.CheckCourseExists(courseID); err != nil {
+		return nil, fmt.Errorf("failed to verify course: %v", err)
 	}
 
-	return inscription, nil
+	return s.repository.GetWaitlistByCourse(ctx, courseID)
+}
+
+func (s *Service) GetWaitlistByUser(ctx context.Context, userID uint) ([]domain.WaitlistEntry, error) {
+	return s.repository.GetWaitlistByUser(ctx, userID)
 }
 
-func (s *Service) GetInscriptions(ctx context.Context) ([]domain.Inscription, error) {
-	return s.repository.GetInscriptions(ctx)
+func (s *Service) GetInscriptions(ctx context.Context, statuses []domain.Status) ([]domain.Inscription, error) {
+	return s.repository.GetInscriptions(ctx, statuses)
 }
 
-func (s *Service) GetInscriptionsByUser(ctx context.Context, userID uint) ([]domain.Inscription, error) {
-	return s.repository.GetInscriptionsByUser(ctx, userID)
+func (s *Service) GetInscriptionsByUser(ctx context.Context, userID uint, statuses []domain.Status) ([]domain.Inscription, error) {
+	return s.repository.GetInscriptionsByUser(ctx, userID, statuses)
 }
 
-func (s *Service) GetInscriptionsByCourse(ctx context.Context, courseID uint) ([]domain.Inscription, error) {
+func (s *Service) GetInscriptionsByCourse(ctx context.Context, courseID uint, statuses []domain.Status) ([]domain.Inscription, error) {
 	// Verificar si el curso existe
 	if err := s.httpClient.CheckCourseExists(courseID); err != nil {
 		return nil, fmt.Errorf("failed to verify course: %v", err)
 	}
 
-	return s.repository.GetInscriptionsByCourse(ctx, courseID)
+	return s.repository.GetInscriptionsByCourse(ctx, courseID, statuses)
 }
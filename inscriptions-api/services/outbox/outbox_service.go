@@ -0,0 +1,43 @@
+package service
+
+import (
+	"events"
+	"events/outbox"
+	"fmt"
+	dao "inscriptions-api/DAOs/outbox"
+)
+
+type Queue interface {
+	Publish(event events.InscriptionEvent) error
+}
+
+// NewRelay relay que publica en RabbitMQ los eventos de inscripciones registrados en el outbox
+func NewRelay(repository outbox.Store[dao.EventModel], queue Queue, config outbox.Config) outbox.Relay[dao.EventModel] {
+	return outbox.NewRelay(repository, func(event dao.EventModel) error {
+		published := inscriptionEvent(event)
+		if err := published.Validate(); err != nil {
+			return fmt.Errorf("%w: evento %s inválido: %v", outbox.ErrRejected, event.EventID, err)
+		}
+		if err := queue.Publish(published); err != nil {
+			return fmt.Errorf("error al publicar el evento %s: %w", event.EventID, err)
+		}
+		return nil
+	}, config)
+}
+
+// inscriptionEvent convierte el evento guardado en el outbox al contrato de eventos
+func inscriptionEvent(event dao.EventModel) events.InscriptionEvent {
+	return events.InscriptionEvent{
+		EventID:       event.EventID,
+		SchemaVersion: events.InscriptionSchemaVersion,
+		Type:          events.InscriptionEventType(event.Type),
+		InscriptionID: int64(event.InscriptionID),
+		UserID:        int64(event.UserID),
+		CourseID:      int64(event.CourseID),
+		Timestamp:     event.CreatedAt.UTC(),
+		Reason:        event.Reason,
+		// Los cupos se informan ya calculados, así search-api no consulta esta API
+		SeatsRemaining: event.SeatsRemaining,
+		SeatsVersion:   event.SeatsVersion,
+	}
+}
@@ -2,95 +2,422 @@ package queues
 
 import (
 	"encoding/json"
+	"events"
 	"fmt"
 	"log"
-	"search-api/domain/courses"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
 
+// Espera máxima entre intentos de reconexión con RabbitMQ
+const maxReconnectDelay = 30 * time.Second
+
 // RabbitConfig define la configuración para conectarse a RabbitMQ
 type RabbitConfig struct {
-	Host      string
-	Port      string
-	Username  string
-	Password  string
-	QueueName string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Topology events.Topology
+	// Los mensajes se procesan en lotes de hasta BatchSize, esperando como mucho
+	// BatchWindow desde el primer mensaje del lote
+	BatchSize   int
+	BatchWindow time.Duration
 }
 
-// Rabbit representa una conexión de RabbitMQ
+// Rabbit representa una conexión de RabbitMQ que se restablece automáticamente
+// cuando la conexión o el canal se pierden
 type Rabbit struct {
-	connection *amqp.Connection
-	channel    *amqp.Channel
-	queue      amqp.Queue
+	config       RabbitConfig
+	mu           sync.Mutex
+	closed       bool
+	connection   *amqp.Connection
+	channel      *amqp.Channel
+	adminChannel *amqp.Channel // Canal separado para inspeccionar los mensajes muertos
+	// Canal en modo confirmación para reenviar mensajes a las colas de reintentos,
+	// de mensajes muertos o a la principal
+	publisher *events.ConfirmChannel
+}
+
+// DeadLetter mensaje que agotó sus reintentos o que no cumple el contrato de eventos
+type DeadLetter struct {
+	EventID   string `json:"event_id,omitempty"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error"`
+	Payload   string `json:"payload"`
+}
+
+// NewRabbit crea una nueva conexión a RabbitMQ y declara la topología de eventos de cursos
+func NewRabbit(config RabbitConfig) *Rabbit {
+	rabbit := &Rabbit{config: config}
+	if err := rabbit.connect(); err != nil {
+		log.Fatalf("Error al conectar con RabbitMQ: %v", err)
+	}
+	return rabbit
 }
 
-// NewRabbit crea una nueva conexión a RabbitMQ y declara la cola
-func NewRabbit(config RabbitConfig) Rabbit {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/", config.Username, config.Password, config.Host, config.Port)
+// connect abre la conexión y los canales y declara la topología
+func (rabbit *Rabbit) connect() error {
+	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/", rabbit.config.Username, rabbit.config.Password, rabbit.config.Host, rabbit.config.Port)
 	connection, err := amqp.Dial(connStr)
 	if err != nil {
-		log.Fatalf("Error al conectar con RabbitMQ: %v", err)
+		return fmt.Errorf("error al conectar con RabbitMQ: %w", err)
 	}
 
 	channel, err := connection.Channel()
 	if err != nil {
-		log.Fatalf("Error al crear el canal de RabbitMQ: %v", err)
+		connection.Close()
+		return fmt.Errorf("error al crear el canal de RabbitMQ: %w", err)
 	}
 
-	queue, err := channel.QueueDeclare(
-		config.QueueName,
-		false, // No persistente
-		false, // No autoeliminada
-		false, // No exclusiva
-		false, // No espera
-		nil,
-	)
+	adminChannel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return fmt.Errorf("error al crear el canal de administración de RabbitMQ: %w", err)
+	}
+
+	publisher, err := openPublisher(connection)
 	if err != nil {
-		log.Fatalf("Error al declarar la cola: %v", err)
+		connection.Close()
+		return err
 	}
 
-	return Rabbit{
-		connection: connection,
-		channel:    channel,
-		queue:      queue,
+	if err := rabbit.config.Topology.Declare(channel); err != nil {
+		connection.Close()
+		return fmt.Errorf("error al declarar la topología: %w", err)
 	}
+
+	// Limitar los mensajes sin confirmar que recibe el consumidor a un lote completo
+	if err := channel.Qos(rabbit.batchSize(), 0, false); err != nil {
+		connection.Close()
+		return fmt.Errorf("error al configurar el prefetch: %w", err)
+	}
+
+	rabbit.mu.Lock()
+	defer rabbit.mu.Unlock()
+	// Si solo se cerró un canal la conexión anterior sigue abierta y se descarta
+	if rabbit.connection != nil && !rabbit.connection.IsClosed() {
+		rabbit.connection.Close()
+	}
+	rabbit.connection = connection
+	rabbit.channel = channel
+	rabbit.adminChannel = adminChannel
+	rabbit.publisher = publisher
+	return nil
 }
 
-// StartConsumer inicia la escucha de mensajes en la cola de RabbitMQ
-func (rabbit Rabbit) StartConsumer(handler func(courses.CourseUpdate)) error {
-	messages, err := rabbit.channel.Consume(
-		rabbit.queue.Name,
+// openPublisher abre un canal en modo confirmación sobre la conexión
+func openPublisher(connection *amqp.Connection) (*events.ConfirmChannel, error) {
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el canal de reenvío de RabbitMQ: %w", err)
+	}
+	publisher, err := events.NewConfirmChannel(channel)
+	if err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("error al habilitar las confirmaciones de RabbitMQ: %w", err)
+	}
+	return publisher, nil
+}
+
+// Publish publica el mensaje por el canal de reenvío y espera la confirmación del
+// broker. Si el canal queda inutilizable se reemplaza por uno nuevo.
+func (rabbit *Rabbit) Publish(exchange, key string, msg amqp.Publishing) error {
+	rabbit.mu.Lock()
+	publisher := rabbit.publisher
+	rabbit.mu.Unlock()
+
+	err := publisher.Publish(exchange, key, msg)
+	if err != nil && publisher.Broken() {
+		rabbit.resetPublisher(publisher)
+	}
+	return err
+}
+
+// resetPublisher reemplaza el canal de reenvío si sigue siendo el indicado. Si la
+// conexión se perdió el consumidor la restablece junto con todos los canales.
+func (rabbit *Rabbit) resetPublisher(failed *events.ConfirmChannel) {
+	rabbit.mu.Lock()
+	defer rabbit.mu.Unlock()
+	if rabbit.publisher != failed || rabbit.connection.IsClosed() {
+		return
+	}
+	failed.Close()
+	publisher, err := openPublisher(rabbit.connection)
+	if err != nil {
+		log.Printf("Error al reabrir el canal de reenvío: %v", err)
+		return
+	}
+	rabbit.publisher = publisher
+}
+
+// channels devuelve los canales de la conexión actual
+func (rabbit *Rabbit) channels() (*amqp.Channel, *amqp.Channel) {
+	rabbit.mu.Lock()
+	defer rabbit.mu.Unlock()
+	return rabbit.channel, rabbit.adminChannel
+}
+
+// isClosed indica si la conexión se cerró intencionalmente con Close
+func (rabbit *Rabbit) isClosed() bool {
+	rabbit.mu.Lock()
+	defer rabbit.mu.Unlock()
+	return rabbit.closed
+}
+
+// consume registra el consumidor sobre la cola principal
+func (rabbit *Rabbit) consume() (<-chan amqp.Delivery, error) {
+	channel, _ := rabbit.channels()
+	messages, err := channel.Consume(
+		rabbit.config.Topology.Queue,
 		"",
-		true,  // Auto-acuse de recibo (auto-acknowledge)
+		false, // Acuse de recibo manual
 		false, // No exclusivo
 		false, // No espera
 		false, // No local
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("Error al registrar el consumidor: %v", err)
+		return nil, fmt.Errorf("Error al registrar el consumidor: %v", err)
+	}
+	return messages, nil
+}
+
+// reconnect reintenta conectar y registrar el consumidor con backoff hasta lograrlo.
+// Devuelve false si mientras tanto se llamó a Close.
+func (rabbit *Rabbit) reconnect() (<-chan amqp.Delivery, bool) {
+	delay := time.Second
+	for !rabbit.isClosed() {
+		err := rabbit.connect()
+		if err == nil {
+			var messages <-chan amqp.Delivery
+			if messages, err = rabbit.consume(); err == nil {
+				log.Println("Reconectado a RabbitMQ")
+				return messages, true
+			}
+		}
+
+		log.Printf("Error al reconectar con RabbitMQ: %v. Reintentando en %v...", err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	return nil, false
+}
+
+// Decoder valida un mensaje contra el contrato de eventos y lo convierte en el
+// evento que recibe el manejador, por ejemplo events.Decode
+type Decoder[E any] func(body []byte) (E, error)
+
+// BatchHandler procesa un lote de eventos y devuelve un error por evento, nil si
+// el evento se procesó correctamente
+type BatchHandler[E any] func([]E) []error
+
+// StartConsumer inicia la escucha de mensajes en la cola de RabbitMQ. Los mensajes
+// se agrupan en lotes de hasta BatchSize o durante BatchWindow, se convierten con
+// decode y se pasan juntos al handler. Cada mensaje se confirma solo si su evento
+// se procesó sin error; si falla se reintenta con backoff y, agotados los
+// intentos, se envía a la cola de mensajes muertos, igual que los mensajes que no
+// cumplen el contrato. Si la conexión se pierde el consumidor se reconecta
+// automáticamente.
+func StartConsumer[E any](rabbit *Rabbit, decode Decoder[E], handler BatchHandler[E]) error {
+	messages, err := rabbit.consume()
+	if err != nil {
+		return err
 	}
 
 	// Iniciar una goroutine para procesar mensajes
 	go func() {
-		for msg := range messages {
-			var courseUpdate courses.CourseUpdate
-			if err := json.Unmarshal(msg.Body, &courseUpdate); err != nil {
-				log.Printf("Error al deserializar el mensaje: %v", err)
+		for {
+			batch, open := rabbit.collect(messages)
+			if len(batch) > 0 {
+				handle(rabbit, batch, decode, handler)
+			}
+			if open {
 				continue
 			}
 
-			// Pasar el mensaje al manejador (handler)
-			handler(courseUpdate)
+			// El canal de entregas se cierra cuando se pierde la conexión o el canal
+			if rabbit.isClosed() {
+				return
+			}
+			log.Println("Se perdió la conexión con RabbitMQ, reconectando...")
+			var ok bool
+			if messages, ok = rabbit.reconnect(); !ok {
+				return
+			}
 		}
 	}()
 
 	return nil
 }
 
-// Close cierra la conexión y el canal de RabbitMQ
-func (rabbit Rabbit) Close() {
+// collect espera el primer mensaje y junta los que lleguen durante la ventana del
+// lote, hasta completar su tamaño. Devuelve false si el canal de entregas se cerró.
+func (rabbit *Rabbit) collect(messages <-chan amqp.Delivery) ([]amqp.Delivery, bool) {
+	msg, open := <-messages
+	if !open {
+		return nil, false
+	}
+	batch := []amqp.Delivery{msg}
+
+	window := time.NewTimer(rabbit.config.BatchWindow)
+	defer window.Stop()
+	for len(batch) < rabbit.batchSize() {
+		select {
+		case msg, open := <-messages:
+			if !open {
+				return batch, false
+			}
+			batch = append(batch, msg)
+		case <-window.C:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// batchSize devuelve el tamaño máximo del lote, también usado como prefetch
+func (rabbit *Rabbit) batchSize() int {
+	if rabbit.config.BatchSize <= 0 {
+		return 1
+	}
+	return rabbit.config.BatchSize
+}
+
+// handle decodifica el lote, lo pasa al manejador y confirma, reintenta o envía a
+// la cola de mensajes muertos cada mensaje según su resultado
+func handle[E any](rabbit *Rabbit, batch []amqp.Delivery, decode Decoder[E], handler BatchHandler[E]) {
+	topology := rabbit.config.Topology
+
+	var deliveries []amqp.Delivery
+	var decoded []E
+	for _, msg := range batch {
+		event, err := decode(msg.Body)
+		if err != nil {
+			topology.DeadLetter(rabbit, msg, err)
+			continue
+		}
+		deliveries = append(deliveries, msg)
+		decoded = append(decoded, event)
+	}
+	if len(decoded) == 0 {
+		return
+	}
+
+	// Pasar el lote al manejador (handler)
+	errs := handler(decoded)
+	for i, msg := range deliveries {
+		if err := errs[i]; err != nil {
+			// Los publicadores usan el ID del evento como MessageId
+			topology.Retry(rabbit, msg, msg.MessageId, err)
+			continue
+		}
+		if err := msg.Ack(false); err != nil {
+			log.Printf("Error al confirmar el mensaje: %v", err)
+		}
+	}
+}
+
+// DeadLetters devuelve hasta limit mensajes de la cola de mensajes muertos sin quitarlos de ella
+func (rabbit *Rabbit) DeadLetters(limit int) ([]DeadLetter, error) {
+	var deliveries []amqp.Delivery
+	defer func() {
+		for _, msg := range deliveries {
+			if err := msg.Nack(false, true); err != nil {
+				log.Printf("Error al devolver el mensaje a la cola de mensajes muertos: %v", err)
+			}
+		}
+	}()
+
+	_, adminChannel := rabbit.channels()
+	deadLetters := make([]DeadLetter, 0)
+	for len(deliveries) < limit {
+		msg, ok, err := adminChannel.Get(rabbit.config.Topology.DeadLetterQueue(), false)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la cola de mensajes muertos: %w", err)
+		}
+		if !ok {
+			break
+		}
+		deliveries = append(deliveries, msg)
+		deadLetters = append(deadLetters, toDeadLetter(msg))
+	}
+	return deadLetters, nil
+}
+
+// ReplayDeadLetters devuelve a la cola principal hasta limit mensajes muertos con
+// los intentos reiniciados. Si eventID no está vacío solo se reenvía ese evento.
+func (rabbit *Rabbit) ReplayDeadLetters(limit int, eventID string) (int, error) {
+	var skipped []amqp.Delivery
+	defer func() {
+		for _, msg := range skipped {
+			if err := msg.Nack(false, true); err != nil {
+				log.Printf("Error al devolver el mensaje a la cola de mensajes muertos: %v", err)
+			}
+		}
+	}()
+
+	topology := rabbit.config.Topology
+	_, adminChannel := rabbit.channels()
+	replayed := 0
+	for replayed+len(skipped) < limit {
+		msg, ok, err := adminChannel.Get(topology.DeadLetterQueue(), false)
+		if err != nil {
+			return replayed, fmt.Errorf("error al leer la cola de mensajes muertos: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if eventID != "" && toDeadLetter(msg).EventID != eventID {
+			skipped = append(skipped, msg)
+			continue
+		}
+
+		if err := topology.Replay(rabbit, msg); err != nil {
+			skipped = append(skipped, msg)
+			return replayed, fmt.Errorf("error al reenviar el mensaje: %w", err)
+		}
+		if err := msg.Ack(false); err != nil {
+			return replayed, fmt.Errorf("error al confirmar el mensaje reenviado: %w", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func toDeadLetter(msg amqp.Delivery) DeadLetter {
+	// Se lee el ID sin validar el contrato, el mensaje puede estar malformado
+	var envelope struct {
+		EventID string `json:"event_id"`
+	}
+	_ = json.Unmarshal(msg.Body, &envelope)
+
+	lastError, _ := msg.Headers[events.LastErrorHeader].(string)
+	return DeadLetter{
+		EventID:   envelope.EventID,
+		Attempts:  events.Attempts(msg),
+		LastError: lastError,
+		Payload:   string(msg.Body),
+	}
+}
+
+// Close cierra la conexión y el canal de RabbitMQ sin intentar reconectar
+func (rabbit *Rabbit) Close() {
+	rabbit.mu.Lock()
+	defer rabbit.mu.Unlock()
+	rabbit.closed = true
+
+	if err := rabbit.publisher.Close(); err != nil {
+		log.Printf("Error al cerrar el canal de reenvío de RabbitMQ: %v", err)
+	}
+	if err := rabbit.adminChannel.Close(); err != nil {
+		log.Printf("Error al cerrar el canal de administración de RabbitMQ: %v", err)
+	}
 	if err := rabbit.channel.Close(); err != nil {
 		log.Printf("Error al cerrar el canal de RabbitMQ: %v", err)
 	}
@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"search-api/clients/queues"
+	domain "search-api/domain/courses"
+	"search-api/services/analysis"
+	"search-api/services/reconcile"
+	"search-api/services/reindex"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Límites de mensajes leídos por cada solicitud a la cola de mensajes muertos
+const (
+	defaultDeadLettersLimit = 50
+	maxDeadLettersLimit     = 500
+)
+
+// Cola de mensajes muertos que se usa cuando la solicitud no indica "topology"
+const defaultDeadLettersTopology = "courses"
+
+// DeadLetters define las operaciones sobre la cola de mensajes muertos
+type DeadLetters interface {
+	DeadLetters(limit int) ([]queues.DeadLetter, error)
+	ReplayDeadLetters(limit int, eventID string) (int, error)
+}
+
+// Reindexer define las operaciones de reconstrucción del índice
+type Reindexer interface {
+	Start(fresh bool) (domain.ReindexStatus, error)
+	Status() domain.ReindexStatus
+}
+
+// Reconciler define las operaciones de reconciliación entre la API de cursos y SolR
+type Reconciler interface {
+	Run(ctx context.Context) (domain.DriftReport, error)
+	LastReport() (domain.DriftReport, bool)
+}
+
+// Indexer define la consulta de métricas del indexador de eventos
+type Indexer interface {
+	Metrics() domain.IndexerMetrics
+}
+
+// Analysis define la administración de los sinónimos y stopwords de las búsquedas
+type Analysis interface {
+	Synonyms(ctx context.Context) (map[string][]string, error)
+	AddSynonyms(ctx context.Context, mappings map[string][]string) error
+	DeleteSynonym(ctx context.Context, term string) error
+	Stopwords(ctx context.Context) ([]string, error)
+	AddStopwords(ctx context.Context, words []string) error
+	DeleteStopword(ctx context.Context, word string) error
+	Reload(ctx context.Context) error
+}
+
+// Controller representa el controlador de administración
+type Controller struct {
+	deadLetters map[string]DeadLetters // Colas de mensajes muertos por nombre de topología
+	reindexer   Reindexer
+	reconciler  Reconciler
+	indexer     Indexer
+	analysis    Analysis
+}
+
+// NewController crea una nueva instancia del controlador de administración.
+// deadLetters asocia cada nombre aceptado por el parámetro "topology" con su cola.
+func NewController(deadLetters map[string]DeadLetters, reindexer Reindexer, reconciler Reconciler, indexer Indexer, analysis Analysis) Controller {
+	return Controller{
+		deadLetters: deadLetters,
+		reindexer:   reindexer,
+		reconciler:  reconciler,
+		indexer:     indexer,
+		analysis:    analysis,
+	}
+}
+
+// GetIndexerMetrics maneja las solicitudes GET en /admin/indexer/metrics
+func (controller Controller) GetIndexerMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.indexer.Metrics())
+}
+
+// StartReindex maneja las solicitudes POST en /admin/reindex. Con "fresh=true"
+// indexa en una colección nueva y al terminar cambia el alias (requiere SolrCloud).
+func (controller Controller) StartReindex(c *gin.Context) {
+	fresh, _ := strconv.ParseBool(c.Query("fresh"))
+	status, err := controller.reindexer.Start(fresh)
+	if err != nil {
+		if errors.Is(err, reindex.ErrAlreadyRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": controller.reindexer.Status()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al iniciar la reindexación: %v", err)})
+		return
+	}
+	c.JSON(http.StatusAccepted, status)
+}
+
+// GetReindexStatus maneja las solicitudes GET en /admin/reindex
+func (controller Controller) GetReindexStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.reindexer.Status())
+}
+
+// RunReconciliation maneja las solicitudes POST en /admin/reconciliation.
+// Ejecuta una reconciliación en el momento y devuelve su informe de diferencias.
+func (controller Controller) RunReconciliation(c *gin.Context) {
+	report, err := controller.reconciler.Run(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, reconcile.ErrAlreadyRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error en la reconciliación: %v", err), "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReconciliation maneja las solicitudes GET en /admin/reconciliation
+func (controller Controller) GetReconciliation(c *gin.Context) {
+	report, ok := controller.reconciler.LastReport()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todavía no se ejecutó ninguna reconciliación"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// deadLetterQueue devuelve la cola de mensajes muertos indicada por el parámetro
+// "topology" (courses por defecto). Si no existe responde 400 y devuelve false.
+func (controller Controller) deadLetterQueue(c *gin.Context) (DeadLetters, bool) {
+	topology := c.DefaultQuery("topology", defaultDeadLettersTopology)
+	queue, ok := controller.deadLetters[topology]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Topología desconocida: %q", topology)})
+	}
+	return queue, ok
+}
+
+// GetDeadLetters maneja las solicitudes GET en /admin/dead-letters. Acepta el
+// parámetro opcional "topology" (courses o inscriptions) para elegir la cola.
+func (controller Controller) GetDeadLetters(c *gin.Context) {
+	queue, ok := controller.deadLetterQueue(c)
+	if !ok {
+		return
+	}
+	deadLetters, err := queue.DeadLetters(parseLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al leer los mensajes muertos: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, deadLetters)
+}
+
+// ReplayDeadLetters maneja las solicitudes POST en /admin/dead-letters/replay.
+// Acepta el parámetro opcional "event_id" para reenviar un único evento y
+// "topology", como GET /admin/dead-letters.
+func (controller Controller) ReplayDeadLetters(c *gin.Context) {
+	queue, ok := controller.deadLetterQueue(c)
+	if !ok {
+		return
+	}
+	replayed, err := queue.ReplayDeadLetters(parseLimit(c), c.Query("event_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al reenviar los mensajes muertos: %v", err), "replayed": replayed})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+// GetSynonyms maneja las solicitudes GET en /admin/synonyms
+func (controller Controller) GetSynonyms(c *gin.Context) {
+	synonyms, err := controller.analysis.Synonyms(c.Request.Context())
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synonyms": synonyms})
+}
+
+// AddSynonyms maneja las solicitudes POST en /admin/synonyms, con un cuerpo como
+// {"synonyms": {"js": ["javascript"]}}. Los cambios se aplican con POST /admin/reload.
+func (controller Controller) AddSynonyms(c *gin.Context) {
+	var request struct {
+		Synonyms map[string][]string `json:"synonyms" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cuerpo inválido: %v", err)})
+		return
+	}
+	if err := controller.analysis.AddSynonyms(c.Request.Context(), request.Synonyms); err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteSynonym maneja las solicitudes DELETE en /admin/synonyms/:term
+func (controller Controller) DeleteSynonym(c *gin.Context) {
+	if err := controller.analysis.DeleteSynonym(c.Request.Context(), c.Param("term")); err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetStopwords maneja las solicitudes GET en /admin/stopwords
+func (controller Controller) GetStopwords(c *gin.Context) {
+	stopwords, err := controller.analysis.Stopwords(c.Request.Context())
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stopwords": stopwords})
+}
+
+// AddStopwords maneja las solicitudes POST en /admin/stopwords, con un cuerpo como
+// {"stopwords": ["de", "la"]}. Los cambios se aplican con POST /admin/reload.
+func (controller Controller) AddStopwords(c *gin.Context) {
+	var request struct {
+		Stopwords []string `json:"stopwords" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cuerpo inválido: %v", err)})
+		return
+	}
+	if err := controller.analysis.AddStopwords(c.Request.Context(), request.Stopwords); err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteStopword maneja las solicitudes DELETE en /admin/stopwords/:word
+func (controller Controller) DeleteStopword(c *gin.Context) {
+	if err := controller.analysis.DeleteStopword(c.Request.Context(), c.Param("word")); err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Reload maneja las solicitudes POST en /admin/reload. Recarga la colección para
+// que las búsquedas usen los sinónimos y stopwords actuales.
+func (controller Controller) Reload(c *gin.Context) {
+	if err := controller.analysis.Reload(c.Request.Context()); err != nil {
+		analysisError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reloaded": true})
+}
+
+// analysisError responde con el código HTTP que corresponde a un error de la
+// administración de sinónimos y stopwords
+func analysisError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, analysis.ErrEmptyList):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, analysis.ErrTermNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "El término no está en la lista"})
+	case errors.Is(err, analysis.ErrUnsupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "El motor de búsqueda configurado no admite sinónimos ni stopwords"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseLimit lee el parámetro "limit" aplicando el valor por defecto y el máximo
+func parseLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultDeadLettersLimit
+	}
+	if limit > maxDeadLettersLimit {
+		return maxDeadLettersLimit
+	}
+	return limit
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	domain "search-api/domain/analytics"
+	analyticsService "search-api/services/analytics"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Período y cantidad de consultas por defecto y máximas del informe
+const (
+	defaultReportDays  = 7
+	defaultReportLimit = 20
+	maxReportLimit     = 100
+)
+
+// Service define la interfaz del servicio de analíticas de búsqueda
+type Service interface {
+	RecordClick(event domain.ClickEvent) error
+	Report(days int, limit int) (domain.Report, error)
+}
+
+// Controller representa el controlador de analíticas de búsqueda
+type Controller struct {
+	service Service
+}
+
+// NewController crea una nueva instancia del controlador de analíticas
+func NewController(service Service) Controller {
+	return Controller{
+		service: service,
+	}
+}
+
+// Click maneja las solicitudes POST en /search/clicks. El frontend lo envía cuando
+// el usuario abre un resultado, con el "search_id" de la respuesta de /search, la
+// consulta, el curso y su posición en la lista.
+func (controller Controller) Click(c *gin.Context) {
+	var click domain.ClickEvent
+	if err := c.ShouldBindJSON(&click); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Clic inválido: %v", err)})
+		return
+	}
+	if err := controller.service.RecordClick(click); err != nil {
+		if errors.Is(err, analyticsService.ErrInvalidClick) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al registrar el clic: %v", err)})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetReport maneja las solicitudes GET en /admin/analytics. Acepta "days", el
+// período del informe, y "limit", la cantidad de consultas de cada lista.
+func (controller Controller) GetReport(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultReportDays)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro 'days' debe ser un número"})
+		return
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultReportLimit
+	}
+	if limit > maxReportLimit {
+		limit = maxReportLimit
+	}
+
+	report, err := controller.service.Report(days, limit)
+	if err != nil {
+		if errors.Is(err, analyticsService.ErrInvalidPeriod) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al armar el informe: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -2,17 +2,37 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"search-api/domain/courses"
+	searchService "search-api/services/search"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Cantidad de resultados por página por defecto de /search; el máximo lo define el servicio
+const defaultSearchLimit = 10
+
+// Cantidad de sugerencias por defecto y máxima de /search/suggest
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+)
+
+// Cantidad de cursos similares por defecto y máxima
+const (
+	defaultSimilarLimit = 5
+	maxSimilarLimit     = 20
+)
+
 // Service define la interfaz del servicio de búsqueda
 type Service interface {
-	Search(ctx context.Context, query string, offset int, limit int) ([]courses.CourseUpdate, error)
+	Search(ctx context.Context, query string, filters courses.SearchFilters, sort string, page courses.SearchPage) (courses.SearchResponse, error)
+	Suggest(ctx context.Context, prefix string, limit int) ([]courses.Suggestion, error)
+	Similar(ctx context.Context, courseID int64, limit int) (courses.SimilarCoursesResponse, error)
 }
 
 // Controller representa el controlador de búsqueda
@@ -27,34 +47,141 @@ func NewController(service Service) Controller {
 	}
 }
 
-// Search maneja las solicitudes GET en el endpoint /search
+// Search maneja las solicitudes GET en el endpoint /search. El parámetro "q"
+// acepta frases entre comillas, exclusiones con "-" y el filtro "category:"
+// (ver domain.SearchQuery), por ejemplo: q=go "concurrencia avanzada" -java category:backend
+//
+// Los filtros de las facetas se pasan como parámetros y pueden repetirse:
+// category, instructor_id, duration y rating (calificación mínima). "q" puede
+// omitirse si se aplica algún filtro.
+//
+// "sort" admite relevance, rating, newest o name; por defecto se ordena por
+// relevancia ponderada por la calificación del curso. Cada resultado incluye su
+// relevancia ("score") y fragmentos de nombre y descripción resaltados.
+//
+// La respuesta incluye el total de coincidencias, "offset", "limit" y el enlace
+// "next" a la página siguiente. Para recorrer muchos resultados se pagina por
+// cursor: cursor=* pide la primera página y cada respuesta trae "next_cursor".
+// El "search_id" de la respuesta identifica la búsqueda al registrar clics.
 func (controller Controller) Search(c *gin.Context) {
 	// Parsear el parámetro de búsqueda "query" de la URL
 	query := c.Query("q")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro 'q' es obligatorio"})
+
+	// Parsear los filtros de las facetas
+	var filters courses.SearchFilters
+	if err := c.ShouldBindQuery(&filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Filtros inválidos: %v", err)})
 		return
 	}
 
-	// Parsear el parámetro "offset" de la URL
-	offset, err := strconv.Atoi(c.Query("offset"))
-	if err != nil {
-		offset = 0 // Valor por defecto si no se proporciona
+	// Parsear los parámetros de paginación "offset", "limit" y "cursor" de la URL
+	page := courses.SearchPage{Limit: defaultSearchLimit, Cursor: c.Query("cursor"), SearchID: c.Query("search_id")}
+	var err error
+	if value, ok := c.GetQuery("offset"); ok {
+		if page.Offset, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro 'offset' debe ser un número"})
+			return
+		}
 	}
-
-	// Parsear el parámetro "limit" de la URL
-	limit, err := strconv.Atoi(c.Query("limit"))
-	if err != nil || limit <= 0 {
-		limit = 10 // Valor por defecto si no se proporciona o es inválido
+	if value, ok := c.GetQuery("limit"); ok {
+		if page.Limit, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro 'limit' debe ser un número"})
+			return
+		}
 	}
 
 	// Llamar al servicio de búsqueda
-	results, err := controller.service.Search(c.Request.Context(), query, offset, limit)
+	results, err := controller.service.Search(c.Request.Context(), query, filters, c.Query("sort"), page)
 	if err != nil {
+		if errors.Is(err, searchService.ErrEmptyQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere el parámetro 'q' o algún filtro"})
+			return
+		}
+		if errors.Is(err, searchService.ErrInvalidSort) || errors.Is(err, searchService.ErrInvalidPage) ||
+			errors.Is(err, searchService.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error en la búsqueda: %v", err)})
 		return
 	}
 
+	results.Next = nextLink(c, page, results)
+
 	// Enviar los resultados como respuesta JSON
 	c.JSON(http.StatusOK, results)
 }
+
+// nextLink arma el enlace a la página siguiente con los mismos parámetros de la
+// búsqueda, avanzando el offset o el cursor. Devuelve "" en la última página.
+func nextLink(c *gin.Context, page courses.SearchPage, results courses.SearchResponse) string {
+	params := c.Request.URL.Query()
+	switch {
+	case page.Cursor != "":
+		if results.NextCursor == "" {
+			return ""
+		}
+		params.Set("cursor", results.NextCursor)
+	case int64(page.Offset+page.Limit) < results.Total:
+		params.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+	default:
+		return ""
+	}
+	params.Set("limit", strconv.Itoa(page.Limit))
+	params.Set("search_id", results.SearchID)
+	return c.Request.URL.Path + "?" + params.Encode()
+}
+
+// Suggest maneja las solicitudes GET en el endpoint /search/suggest. Devuelve
+// nombres de cursos y categorías que completan el texto del parámetro "q".
+func (controller Controller) Suggest(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro 'q' es obligatorio"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+
+	suggestions, err := controller.service.Suggest(c.Request.Context(), prefix, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al obtener sugerencias: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// Similar maneja las solicitudes GET en el endpoint /search/courses/:id/similar.
+// Recomienda cursos parecidos al indicado, sin incluirlo y sin cursos completos.
+func (controller Controller) Similar(c *gin.Context) {
+	courseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de curso inválido"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSimilarLimit
+	}
+	if limit > maxSimilarLimit {
+		limit = maxSimilarLimit
+	}
+
+	similar, err := controller.service.Similar(c.Request.Context(), courseID, limit)
+	if err != nil {
+		if errors.Is(err, searchService.ErrCourseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Curso no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error al buscar cursos similares: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, similar)
+}
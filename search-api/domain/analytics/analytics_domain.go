@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"search-api/domain/courses"
+	"time"
+)
+
+// SearchEvent búsqueda realizada por un usuario, registrada al pedir la primera página
+type SearchEvent struct {
+	SearchID      string                `json:"search_id"`
+	Query         string                `json:"query"`
+	Filters       courses.SearchFilters `json:"filters"`
+	Sort          string                `json:"sort,omitempty"`
+	Hits          int64                 `json:"hits"`
+	LatencyMillis float64               `json:"latency_ms"`
+	Timestamp     time.Time             `json:"timestamp"`
+}
+
+// ClickEvent clic de un usuario en un resultado de búsqueda, enviado por el frontend
+type ClickEvent struct {
+	SearchID  string    `json:"search_id" binding:"required"` // Devuelto en la respuesta de /search
+	Query     string    `json:"query"`
+	CourseID  int64     `json:"course_id" binding:"required"`
+	Position  int       `json:"position"` // Posición del resultado en la lista, desde 1
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Record línea del almacenamiento de analíticas: una búsqueda o un clic
+type Record struct {
+	Search *SearchEvent `json:"search,omitempty"`
+	Click  *ClickEvent  `json:"click,omitempty"`
+}
+
+// QueryStats métricas agregadas de una consulta en el período del informe
+type QueryStats struct {
+	Query            string  `json:"query"`
+	Searches         int64   `json:"searches"`
+	ZeroResults      int64   `json:"zero_results"`
+	Clicks           int64   `json:"clicks"`
+	ClickedSearches  int64   `json:"clicked_searches"`
+	CTR              float64 `json:"ctr"` // Búsquedas con al menos un clic sobre el total de búsquedas
+	AvgHits          float64 `json:"avg_hits"`
+	AvgLatencyMillis float64 `json:"avg_latency_ms"`
+}
+
+// Report informe de /admin/analytics con las consultas más buscadas y las que no
+// encuentran resultados
+type Report struct {
+	From               time.Time    `json:"from"`
+	To                 time.Time    `json:"to"`
+	Searches           int64        `json:"searches"`
+	ZeroResultSearches int64        `json:"zero_result_searches"`
+	Clicks             int64        `json:"clicks"`
+	CTR                float64      `json:"ctr"`
+	AvgLatencyMillis   float64      `json:"avg_latency_ms"`
+	TopQueries         []QueryStats `json:"top_queries"`
+	ZeroResultQueries  []QueryStats `json:"zero_result_queries"`
+}
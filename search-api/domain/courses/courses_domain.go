@@ -1,11 +1,248 @@
 package courses
 
-// CourseUpdate representa una actualización de curso enviada a través de RabbitMQ
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"events"
+	"time"
+)
+
+// ErrCourseNotFound se devuelve cuando la API de cursos no tiene el curso pedido
+var ErrCourseNotFound = errors.New("course not found")
+
+// CourseUpdate representa un curso indexado en SolR, con todos los datos que
+// muestra la tarjeta del curso en los resultados de búsqueda. Los mensajes de
+// RabbitMQ se describen en el contrato compartido events.CourseEvent.
 type CourseUpdate struct {
-	Operation   string `json:"operation"`   // Tipo de operación: "CREATE", "UPDATE", "DELETE"
-	CourseID    int64  `json:"course_id"`   // Identificador único del curso
-	Name        string `json:"name"`        // Nombre del curso (para "CREATE" o "UPDATE")
-	Category    string `json:"category"`    // Categoría del curso (para "CREATE" o "UPDATE")
-	Description string `json:"description"` // Descripción del curso (para "CREATE" o "UPDATE")
-	// Añadir más campos si es necesario
+	CourseID       int64   `json:"course_id"`       // Identificador único del curso
+	Name           string  `json:"name"`            // Nombre del curso
+	Category       string  `json:"category"`        // Categoría del curso
+	Description    string  `json:"description"`     // Descripción del curso
+	InstructorID   int64   `json:"instructor_id"`   // Instructor del curso
+	Rating         float64 `json:"rating"`          // Calificación promedio del curso
+	Duration       string  `json:"duration"`        // Duración del curso
+	ImageID        string  `json:"image_id"`        // Imagen de portada del curso
+	Capacity       int     `json:"capacity"`        // Cupo total del curso
+	CommentCount   int     `json:"comment_count"`   // Cantidad de comentarios del curso
+	CreatedAt      int64   `json:"created_at"`      // Fecha de creación (Unix)
+	SeatsRemaining int     `json:"seats_remaining"` // Derivado: cupo menos inscripciones
+	Version        int64   `json:"version"`         // Versión del curso en la API de cursos
+	SeatsVersion   int64   `json:"-"`               // Versión de los cupos en la API de inscripciones
+}
+
+// FromSnapshot toma del curso publicado por la API de cursos los campos que se
+// indexan en SolR. Los cupos disponibles se completan aparte con WithSeats.
+func FromSnapshot(course events.CourseSnapshot) CourseUpdate {
+	return CourseUpdate{
+		CourseID:     course.ID,
+		Name:         course.Name,
+		Category:     course.Category,
+		Description:  course.Description,
+		InstructorID: course.InstructorID,
+		Rating:       course.Rating,
+		Duration:     course.Duration,
+		ImageID:      course.ImageID,
+		Capacity:     course.Capacity,
+		CommentCount: course.CommentCount,
+		CreatedAt:    course.CreatedAt,
+		Version:      course.Version,
+	}
+}
+
+// WithSeats calcula los cupos disponibles a partir de las inscripciones del curso
+func (course CourseUpdate) WithSeats(inscriptions int) CourseUpdate {
+	course.SeatsRemaining = course.Capacity - inscriptions
+	if course.SeatsRemaining < 0 {
+		course.SeatsRemaining = 0
+	}
+	return course
+}
+
+// ContentHash resume el contenido indexado del curso; si cambia algún campo
+// cambia el hash, lo que permite detectar documentos desactualizados en SolR.
+// Los cupos disponibles no vienen de la API de cursos y cambian con cada
+// inscripción, por lo que la reconciliación los compara por separado.
+func (course CourseUpdate) ContentHash() string {
+	course.SeatsRemaining = 0
+	bytes, _ := json.Marshal(course)
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// SearchQuery consulta de búsqueda ya interpretada. Se arma a partir del texto
+// del usuario con el mini lenguaje de consultas:
+//
+//	palabra              el término debe aparecer en el nombre, descripción o categoría
+//	"frase exacta"       las palabras deben aparecer juntas y en ese orden
+//	-palabra / -"frase"  excluye los cursos que la contengan
+//	category:valor       filtra por categoría (admite category:"dos palabras")
+type SearchQuery struct {
+	Terms      []string `json:"terms,omitempty"`
+	Phrases    []string `json:"phrases,omitempty"`
+	Excluded   []string `json:"excluded,omitempty"` // Términos o frases excluidos
+	Categories []string `json:"categories,omitempty"`
+}
+
+// IsEmpty indica si la consulta no tiene ningún criterio
+func (query SearchQuery) IsEmpty() bool {
+	return len(query.Terms) == 0 && len(query.Phrases) == 0 && len(query.Excluded) == 0 && len(query.Categories) == 0
+}
+
+// SearchFilters filtros de las facetas, enviados como parámetros de /search
+type SearchFilters struct {
+	Categories    []string `form:"category"`
+	InstructorIDs []int64  `form:"instructor_id"`
+	MinRating     float64  `form:"rating"`
+	Durations     []string `form:"duration"`
+}
+
+// IsEmpty indica si no se aplicó ningún filtro
+func (filters SearchFilters) IsEmpty() bool {
+	return len(filters.Categories) == 0 && len(filters.InstructorIDs) == 0 && filters.MinRating == 0 && len(filters.Durations) == 0
+}
+
+// FacetCount cantidad de cursos para un valor de una faceta
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets conteos de cada faceta para los resultados de una búsqueda. El conteo de
+// cada faceta ignora su propio filtro, para poder ofrecer los demás valores.
+type Facets struct {
+	Categories  []FacetCount `json:"categories"`
+	Instructors []FacetCount `json:"instructors"`
+	Ratings     []FacetCount `json:"ratings"` // Cursos con calificación mayor o igual al valor
+	Durations   []FacetCount `json:"durations"`
+}
+
+// Órdenes de /search. Sin orden se usa la relevancia ponderada por calificación.
+const (
+	SortRelevance = "relevance" // Solo la relevancia del texto
+	SortRating    = "rating"    // Mejor calificados primero
+	SortNewest    = "newest"    // Más recientes primero
+	SortName      = "name"      // Alfabético por nombre
+)
+
+// SearchHit curso encontrado junto con su relevancia y los fragmentos resaltados
+type SearchHit struct {
+	CourseUpdate
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"` // Fragmentos de name y description con <em>
+}
+
+// CursorStart es el cursor con el que se pide la primera página de una paginación profunda
+const CursorStart = "*"
+
+// SearchPage indica qué página de resultados pedir: por posición, con Offset y
+// Limit, o a partir del cursor devuelto por la página anterior, que evita el
+// costo de saltear muchos resultados al recorrer búsquedas grandes
+type SearchPage struct {
+	Offset int
+	Limit  int
+	Cursor string // Vacío para paginar por offset; CursorStart para empezar a paginar por cursor
+	// Búsqueda a la que pertenece una página siguiente, para atribuirle los clics
+	SearchID string
+}
+
+// IsFirst indica si es la primera página de una búsqueda
+func (page SearchPage) IsFirst() bool {
+	return page.Offset == 0 && (page.Cursor == "" || page.Cursor == CursorStart)
+}
+
+// SearchResponse respuesta de /search con el total de coincidencias, los datos
+// de paginación y las facetas
+type SearchResponse struct {
+	SearchID   string      `json:"search_id"` // Identifica la búsqueda al registrar clics
+	Total      int64       `json:"total"`
+	Offset     int         `json:"offset"`
+	Limit      int         `json:"limit"`
+	Results    []SearchHit `json:"results"`
+	Next       string      `json:"next,omitempty"`        // Enlace a la página siguiente, si la hay
+	NextCursor string      `json:"next_cursor,omitempty"` // Cursor de la página siguiente al paginar por cursor
+	Facets     Facets      `json:"facets"`
+	DidYouMean string      `json:"did_you_mean,omitempty"` // Corrección sugerida cuando hay pocos resultados
+}
+
+// SimilarCoursesResponse respuesta de /search/courses/:id/similar
+type SimilarCoursesResponse struct {
+	Total   int64       `json:"total"`
+	Results []SearchHit `json:"results"`
+}
+
+// Tipos de sugerencia del autocompletado
+const (
+	SuggestionName     = "name"
+	SuggestionCategory = "category"
+)
+
+// Suggestion sugerencia del autocompletado de /search/suggest
+type Suggestion struct {
+	Text string `json:"text"`
+	Type string `json:"type"` // "name" o "category"
+}
+
+// IndexerMetrics métricas de rendimiento del consumidor que indexa los eventos de cursos
+type IndexerMetrics struct {
+	StartedAt       time.Time  `json:"started_at"`
+	Batches         int64      `json:"batches"`           // Lotes enviados a SolR
+	Events          int64      `json:"events"`            // Eventos procesados
+	Indexed         int64      `json:"indexed"`           // Documentos agregados o reemplazados
+	Deleted         int64      `json:"deleted"`           // Documentos eliminados
+	Failed          int64      `json:"failed"`            // Eventos que terminaron con error
+	Fetched         int64      `json:"fetched"`           // Cursos consultados a la API de cursos por venir en eventos sin snapshot
+	Outdated        int64      `json:"outdated"`          // Snapshots descartados por ser más viejos que el documento indexado
+	AvgBatchSize    float64    `json:"avg_batch_size"`    // Eventos por lote
+	EventsPerSecond float64    `json:"events_per_second"` // Promedio desde el inicio
+	LastBatchSize   int        `json:"last_batch_size"`
+	LastBatchMillis int64      `json:"last_batch_millis"` // Duración del último lote
+	LastBatchAt     *time.Time `json:"last_batch_at,omitempty"`
+}
+
+// ReindexStatus estado y progreso de una reindexación completa del índice
+type ReindexStatus struct {
+	Running    bool       `json:"running"`
+	Fresh      bool       `json:"fresh"`      // Si se indexa en una colección nueva y luego se cambia el alias
+	Collection string     `json:"collection"` // Colección en la que se están indexando los cursos
+	Indexed    int        `json:"indexed"`
+	Total      int64      `json:"total"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// IndexedVersion versiones de un curso indexado: la del curso en la API de cursos
+// y la de sus cupos en la API de inscripciones
+type IndexedVersion struct {
+	Course int64
+	Seats  int64
+}
+
+// Seats cupos disponibles de un curso informados por un evento de inscripciones
+type Seats struct {
+	Remaining int
+	Version   int64
+}
+
+// IndexedCourse estado de un curso en el índice que compara la reconciliación
+type IndexedCourse struct {
+	ContentHash    string
+	SeatsRemaining int
+	SeatsVersion   int64
+}
+
+// DriftReport resultado de una reconciliación entre la API de cursos y SolR
+type DriftReport struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Courses    int        `json:"courses"`   // Cursos en la API de cursos
+	Documents  int        `json:"documents"` // Documentos en SolR
+	Missing    []int64    `json:"missing"`   // Cursos que no estaban indexados
+	Stale      []int64    `json:"stale"`     // Documentos con contenido desactualizado
+	Seats      []int64    `json:"seats"`     // Documentos con cupos disponibles desactualizados
+	Orphans    []int64    `json:"orphans"`   // Documentos de cursos que ya no existen
+	Repaired   bool       `json:"repaired"`
+	Error      string     `json:"error,omitempty"`
 }
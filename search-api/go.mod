@@ -33,6 +33,7 @@ require (
 )
 
 require (
+	events v0.0.0
 	github.com/gin-gonic/gin v1.10.0
 	github.com/go-sql-driver/mysql v1.7.0 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
@@ -42,3 +43,5 @@ require (
 	golang.org/x/text v0.15.0 // indirect
 	gorm.io/gorm v1.25.12
 )
+
+replace events => ../events
@@ -1,55 +1,226 @@
 package main
 
 import (
+	"context"
+	"events"
+	"flag"
 	"log"
+	"os"
 	"search-api/clients/queues"
+	adminController "search-api/controllers/admin"
+	analyticsController "search-api/controllers/analytics"
 	searchController "search-api/controllers/search"
+	analyticsRepo "search-api/repositories/analytics"
 	"search-api/repositories/courses"
+	"search-api/repositories/inscriptions"
+	analysisService "search-api/services/analysis"
+	analyticsService "search-api/services/analytics"
+	reconcileService "search-api/services/reconcile"
+	reindexService "search-api/services/reindex"
 	searchService "search-api/services/search"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Cantidad de cursos por lote al reindexar
+const reindexBatchSize = 100
+
+// Tamaño máximo y ventana de los lotes de eventos que se indexan juntos
+const (
+	indexBatchSize   = 100
+	indexBatchWindow = 500 * time.Millisecond
+)
+
+// Frecuencia con la que se compara el índice de SolR con la API de cursos
+const reconcileInterval = 10 * time.Minute
+
+// Directorio de los archivos de analíticas de búsqueda y tiempo que se conservan
+const (
+	analyticsDir       = "analytics"
+	analyticsRetention = 90 * 24 * time.Hour
+)
+
+// backend reúne las operaciones que usan los servicios de búsqueda, reindexación
+// y reconciliación, y que implementan tanto SolR como el índice en memoria
+type backend interface {
+	searchService.Repository
+	reindexService.Indexer
+	reconcileService.Index
+	analysisService.Repository
+}
+
 func main() {
-	// Configuración de SolR
-	solrRepo := courses.NewSolr(courses.SolrConfig{
-		Host:       "solr",    // SolR host
-		Port:       "8983",    // SolR port
-		Collection: "courses", // Nombre de la colección en SolR
+	// Motor de búsqueda: SolR por defecto, o un índice en memoria con SEARCH_BACKEND=memory
+	// para desarrollo local y pruebas sin SolR
+	var searchRepo backend
+	switch os.Getenv("SEARCH_BACKEND") {
+	case "", "solr":
+		searchRepo = courses.NewSolr(courses.SolrConfig{
+			Host:       "solr",    // SolR host
+			Port:       "8983",    // SolR port
+			Collection: "courses", // Nombre de la colección (o alias) en SolR
+			ConfigSet:  "courses", // Configset para crear colecciones nuevas al reindexar
+			// Los cambios de los eventos se ven en las búsquedas tras un soft commit
+			CommitWithin: time.Second,
+		})
+	case "memory":
+		searchRepo = courses.NewMemory()
+	default:
+		log.Fatalf("SEARCH_BACKEND inválido: %q (valores posibles: solr, memory)", os.Getenv("SEARCH_BACKEND"))
+	}
+
+	// Configuración del cliente HTTP para la API de Cursos: lo usan la reindexación,
+	// la reconciliación y los eventos anteriores al snapshot del curso
+	coursesAPI := courses.NewHTTP(courses.HTTPConfig{
+		Host:       getEnv("COURSES_API_HOST", "courses-api"),
+		Port:       getEnv("COURSES_API_PORT", "8080"),
+		Timeout:    10 * time.Second,
+		Retries:    3,
+		RetryDelay: 500 * time.Millisecond,
 	})
 
+	// Configuración del cliente HTTP para la API de Inscripciones
+	inscriptionsAPI := inscriptions.NewHTTP(inscriptions.HTTPConfig{
+		Host: getEnv("INSCRIPTIONS_API_HOST", "inscriptions-api"),
+		Port: getEnv("INSCRIPTIONS_API_PORT", "8081"),
+	})
+
+	// Subcomando "reindex": reconstruye el índice y termina sin levantar la API
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		if _, inMemory := searchRepo.(courses.Memory); inMemory {
+			log.Fatal("El subcomando reindex requiere SolR: el índice en memoria vive dentro del proceso de la API")
+		}
+		runReindex(searchRepo, coursesAPI, inscriptionsAPI, os.Args[2:])
+		return
+	}
+
 	// Configuración de RabbitMQ
 	eventsQueue := queues.NewRabbit(queues.RabbitConfig{
-		Host:      "rabbitmq",
-		Port:      "5672",
-		Username:  "root",
-		Password:  "root",
-		QueueName: "courses_queue",
+		Host:     "rabbitmq",
+		Port:     "5672",
+		Username: "root",
+		Password: "root",
+		Topology: events.CoursesTopology,
+		// Los eventos se agrupan para enviarlos a SolR en una sola solicitud
+		BatchSize:   indexBatchSize,
+		BatchWindow: indexBatchWindow,
 	})
 
-	// Configuración del cliente HTTP para la API de Cursos
-	coursesAPI := courses.NewHTTP(courses.HTTPConfig{
-		Host: "courses-api",
-		Port: "8081",
+	// Los eventos de inscripciones actualizan los cupos disponibles de su curso
+	inscriptionsQueue := queues.NewRabbit(queues.RabbitConfig{
+		Host:        "rabbitmq",
+		Port:        "5672",
+		Username:    "root",
+		Password:    "root",
+		Topology:    events.InscriptionsTopology,
+		BatchSize:   indexBatchSize,
+		BatchWindow: indexBatchWindow,
 	})
 
+	// Inicialización del servicio de analíticas, con los datos guardados de ejecuciones anteriores
+	analyticsStore, err := analyticsRepo.NewFile(analyticsRepo.FileConfig{Dir: analyticsDir})
+	if err != nil {
+		log.Fatalf("Error al inicializar las analíticas: %v", err)
+	}
+	analyticsService := analyticsService.NewService(analyticsStore, analyticsRetention)
+	if err := analyticsService.Load(); err != nil {
+		log.Fatalf("Error al cargar las analíticas: %v", err)
+	}
+
 	// Inicialización del servicio de búsqueda
-	searchService := searchService.NewService(solrRepo, coursesAPI)
+	searchService := searchService.NewService(searchRepo, coursesAPI, inscriptionsAPI, analyticsService)
+
+	// Inicialización del servicio de reindexación
+	reindexService := reindexService.NewService(searchRepo, coursesAPI, inscriptionsAPI, reindexBatchSize)
+
+	// Inicialización del servicio de reconciliación
+	reconcileService := reconcileService.NewService(searchRepo, coursesAPI, inscriptionsAPI, reindexBatchSize)
+	if _, inMemory := searchRepo.(courses.Memory); inMemory {
+		// El índice en memoria arranca vacío: la primera reconciliación indexa todos los cursos
+		go func() {
+			if _, err := reconcileService.Run(context.Background()); err != nil {
+				log.Printf("Error al cargar el índice en memoria: %v", err)
+			}
+		}()
+	}
+	go reconcileService.Start(context.Background(), reconcileInterval)
+
+	// Inicialización del servicio de sinónimos y stopwords
+	analysisService := analysisService.NewService(searchRepo)
 
 	// Inicialización del controlador de búsqueda
 	searchController := searchController.NewController(searchService)
 
+	// Inicialización del controlador de analíticas
+	analyticsController := analyticsController.NewController(analyticsService)
+
+	// Inicialización del controlador de administración, con las colas de mensajes
+	// muertos que se eligen con el parámetro "topology"
+	deadLetters := map[string]adminController.DeadLetters{
+		"courses":      eventsQueue,
+		"inscriptions": inscriptionsQueue,
+	}
+	adminController := adminController.NewController(deadLetters, reindexService, reconcileService, searchService, analysisService)
+
 	// Lanzar el consumidor de RabbitMQ
-	if err := eventsQueue.StartConsumer(searchService.HandleCourseUpdate); err != nil {
+	if err := queues.StartConsumer(eventsQueue, events.Decode, searchService.HandleCourseUpdates); err != nil {
 		log.Fatalf("Error al ejecutar el consumidor: %v", err)
 	}
+	if err := queues.StartConsumer(inscriptionsQueue, events.DecodeInscription, searchService.HandleInscriptionUpdates); err != nil {
+		log.Fatalf("Error al ejecutar el consumidor de inscripciones: %v", err)
+	}
 
 	// Configuración del router con Gin
 	router := gin.Default()
 	router.GET("/search", searchController.Search)
+	router.GET("/search/suggest", searchController.Suggest)
+	router.GET("/search/courses/:id/similar", searchController.Similar)
+	router.POST("/search/clicks", analyticsController.Click)
+	router.GET("/admin/analytics", analyticsController.GetReport)
+	router.GET("/admin/dead-letters", adminController.GetDeadLetters)
+	router.POST("/admin/dead-letters/replay", adminController.ReplayDeadLetters)
+	router.GET("/admin/reindex", adminController.GetReindexStatus)
+	router.POST("/admin/reindex", adminController.StartReindex)
+	router.GET("/admin/reconciliation", adminController.GetReconciliation)
+	router.POST("/admin/reconciliation", adminController.RunReconciliation)
+	router.GET("/admin/indexer/metrics", adminController.GetIndexerMetrics)
+	router.GET("/admin/synonyms", adminController.GetSynonyms)
+	router.POST("/admin/synonyms", adminController.AddSynonyms)
+	router.DELETE("/admin/synonyms/:term", adminController.DeleteSynonym)
+	router.GET("/admin/stopwords", adminController.GetStopwords)
+	router.POST("/admin/stopwords", adminController.AddStopwords)
+	router.DELETE("/admin/stopwords/:word", adminController.DeleteStopword)
+	router.POST("/admin/reload", adminController.Reload)
 
 	// Ejecutar la API en el puerto 8082
 	if err := router.Run(":8082"); err != nil {
 		log.Fatalf("Error al ejecutar la aplicación: %v", err)
 	}
 }
+
+// runReindex ejecuta el subcomando "reindex [-fresh] [-batch-size N]"
+func runReindex(searchRepo backend, coursesAPI courses.HTTP, inscriptionsAPI inscriptions.HTTP, args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	fresh := flags.Bool("fresh", false, "indexar en una colección nueva y luego cambiar el alias (requiere SolrCloud)")
+	batchSize := flags.Int("batch-size", reindexBatchSize, "cantidad de cursos por lote")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Argumentos inválidos: %v", err)
+	}
+
+	service := reindexService.NewService(searchRepo, coursesAPI, inscriptionsAPI, *batchSize)
+	if err := service.Run(context.Background(), *fresh); err != nil {
+		log.Fatalf("Error en la reindexación: %v", err)
+	}
+	status := service.Status()
+	log.Printf("Reindexación finalizada: %d cursos indexados en %s", status.Indexed, status.Collection)
+}
+
+// getEnv devuelve la variable de entorno o el valor predeterminado si no está definida
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package analytics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"search-api/domain/analytics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formato del nombre de los archivos diarios, siempre en UTC
+const dayLayout = "2006-01-02"
+
+type FileConfig struct {
+	Dir string // Directorio de los archivos de analíticas
+}
+
+// File almacena las búsquedas y clics en archivos JSON Lines locales, uno por día,
+// para que borrar los datos viejos sea simplemente borrar archivos
+type File struct {
+	dir string
+	mu  *sync.Mutex
+}
+
+func NewFile(config FileConfig) (File, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return File{}, fmt.Errorf("Error creating analytics directory %s: %w", config.Dir, err)
+	}
+	return File{dir: config.Dir, mu: &sync.Mutex{}}, nil
+}
+
+// path devuelve el archivo del día del instante dado
+func (store File) path(day time.Time) string {
+	return filepath.Join(store.dir, day.UTC().Format(dayLayout)+".jsonl")
+}
+
+// Append agrega un registro al archivo del día de su evento
+func (store File) Append(record analytics.Record, timestamp time.Time) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Error marshaling analytics record: %w", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	file, err := os.OpenFile(store.path(timestamp), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("Error opening analytics file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Error writing analytics record: %w", err)
+	}
+	return nil
+}
+
+// days devuelve los días con archivo, ordenados del más viejo al más nuevo
+func (store File) days() ([]time.Time, error) {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing analytics files: %w", err)
+	}
+	var days []time.Time
+	for _, entry := range entries {
+		day, err := time.Parse(dayLayout, strings.TrimSuffix(entry.Name(), ".jsonl"))
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// Load recorre en orden los registros de los días a partir de since. Las líneas
+// que no se pueden leer (por ejemplo, una escritura interrumpida) se ignoran.
+func (store File) Load(since time.Time, fn func(analytics.Record)) error {
+	days, err := store.days()
+	if err != nil {
+		return err
+	}
+	first := since.UTC().Truncate(24 * time.Hour)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, day := range days {
+		if day.Before(first) {
+			continue
+		}
+		if err := store.load(store.path(day), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store File) load(path string, fn func(analytics.Record)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error opening analytics file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record analytics.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		fn(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error reading analytics file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Prune borra los archivos de los días anteriores a before
+func (store File) Prune(before time.Time) error {
+	days, err := store.days()
+	if err != nil {
+		return err
+	}
+	limit := before.UTC().Truncate(24 * time.Hour)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, day := range days {
+		if !day.Before(limit) {
+			break
+		}
+		if err := os.Remove(store.path(day)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error deleting analytics file: %w", err)
+		}
+	}
+	return nil
+}
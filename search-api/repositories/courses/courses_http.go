@@ -3,19 +3,28 @@ package courses
 import (
 	"context"
 	"encoding/json"
+	"events"
 	"fmt"
 	"io"
 	"net/http"
 	"search-api/domain/courses" // Importación correcta del paquete
+	"time"
 )
 
 type HTTPConfig struct {
-	Host string
-	Port string
+	Host       string
+	Port       string
+	Timeout    time.Duration // Tiempo máximo de cada solicitud
+	Retries    int           // Reintentos ante errores de red o respuestas 5xx
+	RetryDelay time.Duration // Espera antes del primer reintento, se duplica en cada uno
 }
 
 type HTTP struct {
-	baseURL func(courseID string) string
+	baseURL    func(courseID string) string
+	afterURL   func(afterID int64, pageSize int) string
+	client     *http.Client
+	retries    int
+	retryDelay time.Duration
 }
 
 func NewHTTP(config HTTPConfig) HTTP {
@@ -23,32 +32,111 @@ func NewHTTP(config HTTPConfig) HTTP {
 		baseURL: func(courseID string) string {
 			return fmt.Sprintf("http://%s:%s/courses/%s", config.Host, config.Port, courseID)
 		},
+		afterURL: func(afterID int64, pageSize int) string {
+			return fmt.Sprintf("http://%s:%s/courses?after_id=%d&page_size=%d", config.Host, config.Port, afterID, pageSize)
+		},
+		client:     &http.Client{Timeout: config.Timeout},
+		retries:    config.Retries,
+		retryDelay: config.RetryDelay,
 	}
 }
 
-// GetCourseByID obtiene los detalles de un curso usando su ID
-func (repository HTTP) GetCourseByID(ctx context.Context, id string) (courses.CourseUpdate, error) {
-	resp, err := http.Get(repository.baseURL(id))
+// statusError respuesta de la API de cursos con un código distinto de 200
+type statusError struct {
+	code int
+}
+
+func (err statusError) Error() string {
+	return fmt.Sprintf("received status code %d", err.code)
+}
+
+// Is permite distinguir con errors.Is un curso inexistente de otros errores
+func (err statusError) Is(target error) bool {
+	return target == courses.ErrCourseNotFound && err.code == http.StatusNotFound
+}
+
+// get hace un GET a la API de cursos y devuelve el cuerpo de la respuesta. Los
+// errores de red y las respuestas 5xx se reintentan con espera exponencial; los
+// 4xx no, porque repetir la solicitud no cambiaría el resultado.
+func (repository HTTP) get(ctx context.Context, url string) ([]byte, error) {
+	delay := repository.retryDelay
+	for attempt := 0; ; attempt++ {
+		body, err := repository.fetch(ctx, url)
+		if err == nil {
+			return body, nil
+		}
+		if status, ok := err.(statusError); (ok && status.code < http.StatusInternalServerError) || attempt >= repository.retries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (repository HTTP) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return courses.CourseUpdate{}, fmt.Errorf("Error fetching course (%s): %w", id, err)
+		return nil, err
+	}
+	resp, err := repository.client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return courses.CourseUpdate{}, fmt.Errorf("Failed to fetch course (%s): received status code %d", id, resp.StatusCode)
+		return nil, statusError{code: resp.StatusCode}
 	}
+	return io.ReadAll(resp.Body)
+}
+
+// coursesPage es el sobre paginado que devuelve GET /courses en la API de cursos
+type coursesPage struct {
+	Results []events.CourseSnapshot `json:"results"`
+	Total   int64                   `json:"total"`
+}
 
-	// Lee el cuerpo de la respuesta
-	body, err := io.ReadAll(resp.Body)
+// GetCoursesAfter obtiene hasta pageSize cursos con ID mayor a afterID, en orden de
+// ID, junto con la cantidad de cursos restantes desde afterID. A diferencia de las
+// páginas por número, un curso eliminado durante el recorrido no corre a los
+// siguientes, por lo que ninguno queda sin leer.
+func (repository HTTP) GetCoursesAfter(ctx context.Context, afterID int64, pageSize int) ([]courses.CourseUpdate, int64, error) {
+	data, err := repository.get(ctx, repository.afterURL(afterID, pageSize))
 	if err != nil {
-		return courses.CourseUpdate{}, fmt.Errorf("Error reading response body for course (%s): %w", id, err)
+		return nil, 0, fmt.Errorf("Error fetching courses (after %d): %w", afterID, err)
+	}
+
+	var body coursesPage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, 0, fmt.Errorf("Error unmarshaling courses (after %d): %w", afterID, err)
+	}
+	return body.courses(), body.Total, nil
+}
+
+func (page coursesPage) courses() []courses.CourseUpdate {
+	result := make([]courses.CourseUpdate, 0, len(page.Results))
+	for _, course := range page.Results {
+		result = append(result, courses.FromSnapshot(course))
+	}
+	return result
+}
+
+// GetCourseByID obtiene los detalles de un curso usando su ID
+func (repository HTTP) GetCourseByID(ctx context.Context, id string) (courses.CourseUpdate, error) {
+	data, err := repository.get(ctx, repository.baseURL(id))
+	if err != nil {
+		return courses.CourseUpdate{}, fmt.Errorf("Error fetching course (%s): %w", id, err)
 	}
 
-	// Deserializa los datos del curso en la estructura CourseUpdate
-	var course courses.CourseUpdate
-	if err := json.Unmarshal(body, &course); err != nil {
+	// courses-api responde con la misma forma que el snapshot del contrato de eventos
+	var course events.CourseSnapshot
+	if err := json.Unmarshal(data, &course); err != nil {
 		return courses.CourseUpdate{}, fmt.Errorf("Error unmarshaling course data (%s): %w", id, err)
 	}
 
-	return course, nil
+	return courses.FromSnapshot(course), nil
 }
@@ -0,0 +1,927 @@
+package courses
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"math"
+	"search-api/domain/courses"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrUnsupported is returned by the operations that only make sense on SolrCloud
+var ErrUnsupported = errors.New("operation not supported by the in-memory search backend")
+
+// Field weights, mirroring queryFields and phraseFields of the Solr backend
+var (
+	memoryQueryFields  = map[string]float64{"name": 3, "category": 2, "description": 1}
+	memoryPhraseFields = map[string]float64{"name": 5, "description": 2}
+)
+
+// Maximum length of a highlighted description snippet, like hl.fragsize
+const snippetSize = 150
+
+// Maximum number of terms of the source course used to find similar courses
+const similarTerms = 25
+
+// token is a lowercased word of a field along with its byte offsets
+type token struct {
+	text       string
+	start, end int
+}
+
+// memoryDocument is an indexed course with its analyzed text fields
+type memoryDocument struct {
+	course courses.CourseUpdate
+	fields map[string][]token
+}
+
+// memoryIndex is the state shared by every copy of a Memory backend
+type memoryIndex struct {
+	mu        sync.RWMutex
+	documents map[int64]*memoryDocument
+	postings  map[string]map[int64]bool // term -> ids of the courses containing it
+}
+
+// Memory is an in-process full-text search backend built on an inverted index.
+// It follows the query, filter, sort and pagination semantics of the Solr
+// backend, so search-api can run locally and in tests without Solr.
+type Memory struct {
+	index *memoryIndex
+}
+
+// NewMemory initializes an empty in-memory search backend
+func NewMemory() Memory {
+	return Memory{index: &memoryIndex{
+		documents: make(map[int64]*memoryDocument),
+		postings:  make(map[string]map[int64]bool),
+	}}
+}
+
+// accentFolder removes the accents of Spanish and other latin words, like ASCIIFoldingFilter
+var accentFolder = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"à", "a", "è", "e", "ì", "i", "ò", "o", "ù", "u",
+	"â", "a", "ê", "e", "î", "i", "ô", "o", "û", "u",
+	"ä", "a", "ë", "e", "ï", "i", "ö", "o", "ã", "a", "õ", "o", "ç", "c",
+)
+
+// analyze splits a text into lowercased words without accents, like the text_search field type
+func analyze(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{text: accentFolder.Replace(strings.ToLower(text[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{text: accentFolder.Replace(strings.ToLower(text[start:])), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// words returns only the text of the analyzed tokens
+func words(text string) []string {
+	tokens := analyze(text)
+	result := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, t.text)
+	}
+	return result
+}
+
+// add indexes a course, replacing the previous version. Must be called with the lock held.
+func (index *memoryIndex) add(course courses.CourseUpdate) {
+	index.remove(course.CourseID)
+	doc := &memoryDocument{
+		course: course,
+		fields: map[string][]token{
+			"name":        analyze(course.Name),
+			"category":    analyze(course.Category),
+			"description": analyze(course.Description),
+		},
+	}
+	index.documents[course.CourseID] = doc
+	for _, tokens := range doc.fields {
+		for _, t := range tokens {
+			if index.postings[t.text] == nil {
+				index.postings[t.text] = make(map[int64]bool)
+			}
+			index.postings[t.text][course.CourseID] = true
+		}
+	}
+}
+
+// remove deletes a course from the index. Must be called with the lock held.
+func (index *memoryIndex) remove(id int64) {
+	doc, ok := index.documents[id]
+	if !ok {
+		return
+	}
+	for _, tokens := range doc.fields {
+		for _, t := range tokens {
+			delete(index.postings[t.text], id)
+			if len(index.postings[t.text]) == 0 {
+				delete(index.postings, t.text)
+			}
+		}
+	}
+	delete(index.documents, id)
+}
+
+// Apply adds or replaces the given courses and removes the given ids. Changes are visible immediately.
+func (backend Memory) Apply(ctx context.Context, adds []courses.CourseUpdate, deletes []int64) error {
+	backend.index.mu.Lock()
+	defer backend.index.mu.Unlock()
+	for _, course := range adds {
+		backend.index.add(course)
+	}
+	for _, id := range deletes {
+		backend.index.remove(id)
+	}
+	return nil
+}
+
+// SetSeats updates the seats remaining and their version of the given courses,
+// keyed by course ID. Courses that are not indexed are ignored.
+func (backend Memory) SetSeats(ctx context.Context, seats map[int64]courses.Seats) error {
+	backend.index.mu.Lock()
+	defer backend.index.mu.Unlock()
+	for id, course := range seats {
+		if doc, ok := backend.index.documents[id]; ok {
+			doc.course.SeatsRemaining = course.Remaining
+			doc.course.SeatsVersion = course.Version
+		}
+	}
+	return nil
+}
+
+// IndexBatch adds or replaces several courses. There is a single collection, so it is ignored.
+func (backend Memory) IndexBatch(ctx context.Context, collection string, batch []courses.CourseUpdate) error {
+	return backend.Apply(ctx, batch, nil)
+}
+
+// DeleteBatch removes several courses. There is a single collection, so it is ignored.
+func (backend Memory) DeleteBatch(ctx context.Context, collection string, ids []int64) error {
+	return backend.Apply(ctx, nil, ids)
+}
+
+// Commit does nothing, changes are visible as soon as they are applied
+func (backend Memory) Commit(ctx context.Context, collection string) error {
+	return nil
+}
+
+// CreateCollection is not supported, fresh reindexing needs SolrCloud aliases
+func (backend Memory) CreateCollection(ctx context.Context, name string) error {
+	return ErrUnsupported
+}
+
+// SwapAlias is not supported, fresh reindexing needs SolrCloud aliases
+func (backend Memory) SwapAlias(ctx context.Context, collection string) (string, error) {
+	return "", ErrUnsupported
+}
+
+// DeleteCollection is not supported, fresh reindexing needs SolrCloud aliases
+func (backend Memory) DeleteCollection(ctx context.Context, name string) error {
+	return ErrUnsupported
+}
+
+// Alias returns the name of the single in-memory collection
+func (backend Memory) Alias() string {
+	return "memory"
+}
+
+// Synonyms is not supported, synonyms are managed Solr resources
+func (backend Memory) Synonyms(ctx context.Context) (map[string][]string, error) {
+	return nil, ErrUnsupported
+}
+
+// AddSynonyms is not supported, synonyms are managed Solr resources
+func (backend Memory) AddSynonyms(ctx context.Context, mappings map[string][]string) error {
+	return ErrUnsupported
+}
+
+// DeleteSynonym is not supported, synonyms are managed Solr resources
+func (backend Memory) DeleteSynonym(ctx context.Context, term string) error {
+	return ErrUnsupported
+}
+
+// Stopwords is not supported, stopwords are managed Solr resources
+func (backend Memory) Stopwords(ctx context.Context) ([]string, error) {
+	return nil, ErrUnsupported
+}
+
+// AddStopwords is not supported, stopwords are managed Solr resources
+func (backend Memory) AddStopwords(ctx context.Context, words []string) error {
+	return ErrUnsupported
+}
+
+// DeleteStopword is not supported, stopwords are managed Solr resources
+func (backend Memory) DeleteStopword(ctx context.Context, word string) error {
+	return ErrUnsupported
+}
+
+// Reload does nothing, there is no analyzer configuration to reload
+func (backend Memory) Reload(ctx context.Context) error {
+	return nil
+}
+
+// IndexedCourses returns the content hash, seats remaining and seats version of
+// every indexed course, keyed by course ID
+func (backend Memory) IndexedCourses(ctx context.Context) (map[int64]courses.IndexedCourse, error) {
+	backend.index.mu.RLock()
+	defer backend.index.mu.RUnlock()
+	indexed := make(map[int64]courses.IndexedCourse, len(backend.index.documents))
+	for id, doc := range backend.index.documents {
+		indexed[id] = courses.IndexedCourse{
+			ContentHash:    doc.course.ContentHash(),
+			SeatsRemaining: doc.course.SeatsRemaining,
+			SeatsVersion:   doc.course.SeatsVersion,
+		}
+	}
+	return indexed, nil
+}
+
+// Versions returns the indexed course and seats versions of the given courses,
+// keyed by course ID. Courses that are not indexed are left out.
+func (backend Memory) Versions(ctx context.Context, ids []int64) (map[int64]courses.IndexedVersion, error) {
+	backend.index.mu.RLock()
+	defer backend.index.mu.RUnlock()
+	versions := make(map[int64]courses.IndexedVersion, len(ids))
+	for _, id := range ids {
+		if doc, ok := backend.index.documents[id]; ok {
+			versions[id] = courses.IndexedVersion{Course: doc.course.Version, Seats: doc.course.SeatsVersion}
+		}
+	}
+	return versions, nil
+}
+
+// clause is a term or phrase of the query, already analyzed
+type clause []string
+
+// clauses analyzes terms and phrases. A term that splits into several words
+// (e.g. "a:b") is matched as a phrase.
+func clauses(values ...[]string) []clause {
+	var result []clause
+	for _, list := range values {
+		for _, value := range list {
+			if tokens := words(value); len(tokens) > 0 {
+				result = append(result, clause(tokens))
+			}
+		}
+	}
+	return result
+}
+
+// occurrences counts how many times the clause appears in the field tokens
+func occurrences(tokens []token, c clause) int {
+	count := 0
+	for i := 0; i+len(c) <= len(tokens); i++ {
+		matched := true
+		for j, word := range c {
+			if tokens[i+j].text != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+// contains reports whether the clause appears in any of the searched fields
+func (doc *memoryDocument) contains(c clause) bool {
+	for field := range memoryQueryFields {
+		if occurrences(doc.fields[field], c) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// minimumShouldMatch mirrors minimumMatch ("2<-1 5<80%"): every clause is
+// required up to 2, one may be missing up to 5 and 80% are required above that
+func minimumShouldMatch(clauses int) int {
+	switch {
+	case clauses <= 2:
+		return clauses
+	case clauses <= 5:
+		return clauses - 1
+	default:
+		return int(math.Floor(float64(clauses) * 0.8))
+	}
+}
+
+// idf is the BM25 inverse document frequency of a clause, using its rarest word
+func (index *memoryIndex) idf(c clause) float64 {
+	df := len(index.documents)
+	for _, word := range c {
+		if n := len(index.postings[word]); n < df {
+			df = n
+		}
+	}
+	n := float64(len(index.documents))
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// candidates returns the ids of the courses containing at least one word of the clauses
+func (index *memoryIndex) candidates(positive []clause) map[int64]bool {
+	result := make(map[int64]bool)
+	if len(positive) == 0 {
+		for id := range index.documents {
+			result[id] = true
+		}
+		return result
+	}
+	for _, c := range positive {
+		for id := range index.postings[c[0]] {
+			result[id] = true
+		}
+	}
+	return result
+}
+
+// score computes the edismax-like relevance of a course: for each matching clause
+// the best weighted field counts, plus a bonus when the terms appear as a phrase.
+// Returns false if the course does not match enough clauses.
+func (index *memoryIndex) score(doc *memoryDocument, positive []clause, phrase clause) (float64, bool) {
+	if len(positive) == 0 {
+		return 1, true
+	}
+	score := 0.0
+	matched := 0
+	for _, c := range positive {
+		best := 0.0
+		for field, weight := range memoryQueryFields {
+			if tf := occurrences(doc.fields[field], c); tf > 0 {
+				best = math.Max(best, weight*(1+math.Log(float64(tf)))*index.idf(c))
+			}
+		}
+		if best > 0 {
+			matched++
+			score += best
+		}
+	}
+	if matched < minimumShouldMatch(len(positive)) {
+		return 0, false
+	}
+	if len(phrase) > 1 {
+		for field, weight := range memoryPhraseFields {
+			if occurrences(doc.fields[field], phrase) > 0 {
+				score += weight * index.idf(phrase)
+			}
+		}
+	}
+	return score, true
+}
+
+// facet identifies the facet filters, a facet is counted ignoring its own filter
+type facet int
+
+const (
+	noFacet facet = iota
+	categoryFacet
+	instructorFacet
+	ratingFacet
+	durationFacet
+)
+
+// passes reports whether the course passes the filters, ignoring the excluded facet
+func passes(course courses.CourseUpdate, filters courses.SearchFilters, excluded facet) bool {
+	if excluded != categoryFacet && len(filters.Categories) > 0 && !containsString(filters.Categories, course.Category) {
+		return false
+	}
+	if excluded != instructorFacet && len(filters.InstructorIDs) > 0 {
+		found := false
+		for _, id := range filters.InstructorIDs {
+			found = found || id == course.InstructorID
+		}
+		if !found {
+			return false
+		}
+	}
+	if excluded != ratingFacet && filters.MinRating > 0 && course.Rating < filters.MinRating {
+		return false
+	}
+	if excluded != durationFacet && len(filters.Durations) > 0 && !containsString(filters.Durations, course.Duration) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryHit is a course matching the query, before filters and sorting
+type memoryHit struct {
+	doc   *memoryDocument
+	score float64
+}
+
+// match returns the courses matching the query text, exclusions and category
+// clauses, without applying the facet filters. Must be called with the lock held.
+func (index *memoryIndex) match(query courses.SearchQuery) []memoryHit {
+	positive := clauses(query.Terms, query.Phrases)
+	excluded := clauses(query.Excluded)
+	categories := clauses(query.Categories)
+	var phrase clause
+	for _, term := range query.Terms {
+		phrase = append(phrase, words(term)...)
+	}
+
+	var hits []memoryHit
+	for id := range index.candidates(positive) {
+		doc := index.documents[id]
+		score, ok := index.score(doc, positive, phrase)
+		if !ok {
+			continue
+		}
+		for _, c := range excluded {
+			ok = ok && !doc.contains(c)
+		}
+		for _, c := range categories {
+			ok = ok && occurrences(doc.fields["category"], c) > 0
+		}
+		if ok {
+			hits = append(hits, memoryHit{doc: doc, score: score})
+		}
+	}
+	return hits
+}
+
+// sortHits orders the hits like sortParams does for Solr, breaking ties by id
+func sortHits(hits []courses.SearchHit, order string) {
+	less := func(a, b courses.SearchHit) (bool, bool) {
+		switch order {
+		case courses.SortRating:
+			if a.Rating != b.Rating {
+				return a.Rating > b.Rating, true
+			}
+			if a.Score != b.Score {
+				return a.Score > b.Score, true
+			}
+		case courses.SortNewest:
+			if a.CreatedAt != b.CreatedAt {
+				return a.CreatedAt > b.CreatedAt, true
+			}
+		case courses.SortName:
+			if an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name); an != bn {
+				return an < bn, true
+			}
+		default:
+			if a.Score != b.Score {
+				return a.Score > b.Score, true
+			}
+		}
+		return false, false
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if result, decided := less(hits[i], hits[j]); decided {
+			return result
+		}
+		return hits[i].CourseID < hits[j].CourseID
+	})
+}
+
+// Prefix of the decoded memory cursors, followed by the position of the next result
+const cursorPrefix = "position:"
+
+// encodeCursor returns an opaque cursor pointing to the given position. Unlike
+// Solr's cursorMark it is not stable if the index changes between pages.
+func encodeCursor(position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(position)))
+}
+
+// decodeCursor returns the position a cursor points to, 0 for CursorStart
+func decodeCursor(cursor string) (int, error) {
+	if cursor == courses.CursorStart {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	position, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+	if err != nil || position < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return position, nil
+}
+
+// Search searches the indexed courses with the same semantics as the Solr
+// backend: query language, facet filters and counts, sorting, highlighting,
+// "did you mean" corrections and offset or cursor pagination
+func (backend Memory) Search(ctx context.Context, query courses.SearchQuery, filters courses.SearchFilters, order string, page courses.SearchPage) (courses.SearchResponse, error) {
+	offset := page.Offset
+	if page.Cursor != "" {
+		position, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return courses.SearchResponse{}, err
+		}
+		offset = position
+	}
+
+	index := backend.index
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	matches := index.match(query)
+	highlighted := clauses(query.Terms, query.Phrases)
+
+	hits := make([]courses.SearchHit, 0)
+	for _, match := range matches {
+		if !passes(match.doc.course, filters, noFacet) {
+			continue
+		}
+		score := match.score
+		if order == "" {
+			// Rating-boosted relevance, like ratingBoost
+			score *= 1 + match.doc.course.Rating/5
+		}
+		hits = append(hits, courses.SearchHit{
+			CourseUpdate: match.doc.course,
+			Score:        score,
+			Highlights:   highlights(match.doc, highlighted),
+		})
+	}
+	sortHits(hits, order)
+
+	total := len(hits)
+	if offset > total {
+		offset = total
+	}
+	hits = hits[offset:]
+	if page.Limit < len(hits) {
+		hits = hits[:page.Limit]
+	}
+
+	response := courses.SearchResponse{
+		Total:      int64(total),
+		Offset:     page.Offset,
+		Limit:      page.Limit,
+		Results:    hits,
+		Facets:     facetCounts(matches, filters),
+		DidYouMean: index.didYouMean(query, filters),
+	}
+	if page.Cursor != "" && offset+len(hits) < total {
+		response.NextCursor = encodeCursor(offset + len(hits))
+	}
+	return response, nil
+}
+
+// facetCounts counts the values of each facet among the matches, applying every
+// filter except the one of the facet being counted
+func facetCounts(matches []memoryHit, filters courses.SearchFilters) courses.Facets {
+	categories := make(map[string]int64)
+	instructors := make(map[string]int64)
+	durations := make(map[string]int64)
+	ratings := make([]int64, len(ratingBuckets))
+	for _, match := range matches {
+		course := match.doc.course
+		if passes(course, filters, categoryFacet) && course.Category != "" {
+			categories[course.Category]++
+		}
+		if passes(course, filters, instructorFacet) {
+			instructors[strconv.FormatInt(course.InstructorID, 10)]++
+		}
+		if passes(course, filters, durationFacet) && course.Duration != "" {
+			durations[course.Duration]++
+		}
+		if passes(course, filters, ratingFacet) {
+			for i, bucket := range ratingBuckets {
+				if course.Rating >= bucket {
+					ratings[i]++
+				}
+			}
+		}
+	}
+
+	facets := courses.Facets{
+		Categories:  termCounts(categories),
+		Instructors: termCounts(instructors),
+		Ratings:     make([]courses.FacetCount, 0, len(ratingBuckets)),
+		Durations:   termCounts(durations),
+	}
+	for i, bucket := range ratingBuckets {
+		facets.Ratings = append(facets.Ratings, courses.FacetCount{
+			Value: strconv.FormatFloat(bucket, 'f', -1, 64),
+			Count: ratings[i],
+		})
+	}
+	return facets
+}
+
+// termCounts sorts the values of a terms facet by count like Solr, up to facetLimit
+func termCounts(counts map[string]int64) []courses.FacetCount {
+	result := make([]courses.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, courses.FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	if len(result) > facetLimit {
+		result = result[:facetLimit]
+	}
+	return result
+}
+
+// highlights wraps the query words found in the name and description with <em>
+func highlights(doc *memoryDocument, positive []clause) map[string][]string {
+	if len(positive) == 0 {
+		return nil
+	}
+	queryWords := make(map[string]bool)
+	for _, c := range positive {
+		for _, word := range c {
+			queryWords[word] = true
+		}
+	}
+
+	result := make(map[string][]string)
+	for field, text := range map[string]string{"name": doc.course.Name, "description": doc.course.Description} {
+		tokens := doc.fields[field]
+		first := -1
+		for i, t := range tokens {
+			if queryWords[t.text] {
+				first = i
+				break
+			}
+		}
+		if first < 0 {
+			continue
+		}
+
+		// The snippet starts a few words before the first match and ends on a word boundary
+		from := first
+		for from > 0 && tokens[first].start-tokens[from-1].start < snippetSize/3 {
+			from--
+		}
+		start, end := tokens[from].start, len(text)
+		if from == 0 {
+			start = 0
+		}
+		if end-start > snippetSize {
+			end = start
+			for _, t := range tokens[from:] {
+				if t.end-start > snippetSize {
+					break
+				}
+				end = t.end
+			}
+		}
+
+		var snippet strings.Builder
+		position := start
+		for _, t := range tokens[from:] {
+			if t.start >= end {
+				break
+			}
+			if queryWords[t.text] {
+				snippet.WriteString(text[position:t.start])
+				snippet.WriteString("<em>" + text[t.start:t.end] + "</em>")
+				position = t.end
+			}
+		}
+		snippet.WriteString(text[position:end])
+		result[field] = []string{snippet.String()}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// didYouMean replaces the query words missing from the index with the closest
+// indexed word, and only offers the correction if it finds results with the
+// same filters. Must be called with the lock held.
+func (index *memoryIndex) didYouMean(query courses.SearchQuery, filters courses.SearchFilters) string {
+	var original []string
+	for _, value := range append(append([]string{}, query.Terms...), query.Phrases...) {
+		original = append(original, words(value)...)
+	}
+	if len(original) == 0 {
+		return ""
+	}
+
+	corrected := make([]string, len(original))
+	changed := false
+	for i, word := range original {
+		corrected[i] = word
+		if len(index.postings[word]) > 0 || len([]rune(word)) < 3 {
+			continue
+		}
+		if suggestion := index.closestWord(word); suggestion != "" {
+			corrected[i] = suggestion
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	for _, match := range index.match(courses.SearchQuery{Terms: corrected, Categories: query.Categories}) {
+		if passes(match.doc.course, filters, noFacet) {
+			return strings.Join(corrected, " ")
+		}
+	}
+	return ""
+}
+
+// closestWord finds the indexed word within two edits that shares the first
+// letter, preferring fewer edits and then the most frequent word
+func (index *memoryIndex) closestWord(word string) string {
+	runes := []rune(word)
+	best, bestDistance, bestDF := "", 3, 0
+	for candidate, ids := range index.postings {
+		candidateRunes := []rune(candidate)
+		if candidateRunes[0] != runes[0] || abs(len(candidateRunes)-len(runes)) > 2 {
+			continue
+		}
+		distance := editDistance(runes, candidateRunes)
+		if distance < bestDistance || (distance == bestDistance && distance <= 2 && len(ids) > bestDF) ||
+			(distance == bestDistance && len(ids) == bestDF && candidate < best) {
+			best, bestDistance, bestDF = candidate, distance, len(ids)
+		}
+	}
+	if bestDistance > 2 {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two words
+func editDistance(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Suggest returns course names and categories containing a word that starts with
+// the last word of the prefix and every previous word, like the infix suggesters
+func (backend Memory) Suggest(ctx context.Context, prefix string, limit int) ([]courses.Suggestion, error) {
+	prefixWords := words(prefix)
+	if len(prefixWords) == 0 {
+		return []courses.Suggestion{}, nil
+	}
+
+	backend.index.mu.RLock()
+	defer backend.index.mu.RUnlock()
+
+	matchesPrefix := func(tokens []token) bool {
+		for i, word := range prefixWords {
+			found := false
+			for _, t := range tokens {
+				if t.text == word || (i == len(prefixWords)-1 && strings.HasPrefix(t.text, word)) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	var names []courses.CourseUpdate
+	categories := make(map[string]bool)
+	for _, doc := range backend.index.documents {
+		if matchesPrefix(doc.fields["name"]) {
+			names = append(names, doc.course)
+		}
+		if matchesPrefix(doc.fields["category"]) {
+			categories[doc.course.Category] = true
+		}
+	}
+	// Names are weighted by rating, like the weightField of nameSuggester
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Rating != names[j].Rating {
+			return names[i].Rating > names[j].Rating
+		}
+		return names[i].Name < names[j].Name
+	})
+	categoryList := make([]string, 0, len(categories))
+	for category := range categories {
+		categoryList = append(categoryList, category)
+	}
+	sort.Strings(categoryList)
+
+	seen := make(map[string]bool)
+	suggestions := make([]courses.Suggestion, 0)
+	addSuggestion := func(text, kind string) {
+		key := kind + ":" + strings.ToLower(text)
+		if !seen[key] && len(suggestions) < limit {
+			seen[key] = true
+			suggestions = append(suggestions, courses.Suggestion{Text: text, Type: kind})
+		}
+	}
+	for _, course := range names {
+		addSuggestion(course.Name, courses.SuggestionName)
+	}
+	for _, category := range categoryList {
+		addSuggestion(category, courses.SuggestionCategory)
+	}
+	return suggestions, nil
+}
+
+// Similar returns the courses sharing the most relevant words with the given one,
+// leaving out the course itself and the courses without seats remaining
+func (backend Memory) Similar(ctx context.Context, courseID int64, limit int) (courses.SimilarCoursesResponse, error) {
+	index := backend.index
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	source, ok := index.documents[courseID]
+	if !ok {
+		return courses.SimilarCoursesResponse{}, ErrCourseNotFound
+	}
+
+	// The most relevant words of the source course, like the terms MoreLikeThis picks
+	frequencies := make(map[string]int)
+	for field := range memoryQueryFields {
+		for _, t := range source.fields[field] {
+			frequencies[t.text]++
+		}
+	}
+	type weightedTerm struct {
+		text   string
+		weight float64
+	}
+	terms := make([]weightedTerm, 0, len(frequencies))
+	for text, tf := range frequencies {
+		terms = append(terms, weightedTerm{text: text, weight: float64(tf) * index.idf(clause{text})})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].weight != terms[j].weight {
+			return terms[i].weight > terms[j].weight
+		}
+		return terms[i].text < terms[j].text
+	})
+	if len(terms) > similarTerms {
+		terms = terms[:similarTerms]
+	}
+
+	scores := make(map[int64]float64)
+	for _, term := range terms {
+		for id := range index.postings[term.text] {
+			if id != courseID && index.documents[id].course.SeatsRemaining > 0 {
+				scores[id] += term.weight
+			}
+		}
+	}
+
+	hits := make([]courses.SearchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, courses.SearchHit{CourseUpdate: index.documents[id].course, Score: score})
+	}
+	sortHits(hits, courses.SortRelevance)
+
+	total := int64(len(hits))
+	if limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return courses.SimilarCoursesResponse{Total: total, Results: hits}, nil
+}
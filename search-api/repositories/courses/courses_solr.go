@@ -4,21 +4,42 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"search-api/domain/courses"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/stevenferrer/solr-go"
 )
 
+// ErrCourseNotFound is returned when the requested course is not indexed
+var ErrCourseNotFound = errors.New("course not found in the index")
+
+// ErrInvalidCursor is returned when the search cursor was not issued by the index
+var ErrInvalidCursor = errors.New("invalid search cursor")
+
 type SolrConfig struct {
 	Host       string // Solr host
 	Port       string // Solr port
-	Collection string // Solr collection name
+	Collection string // Solr collection name (or alias when reindexing into fresh collections)
+	ConfigSet  string // Configset used to create fresh collections (SolrCloud only)
+	// Maximum time until applied changes are visible to searches (soft commit)
+	CommitWithin time.Duration
 }
 
 type Solr struct {
-	Client     *solr.JSONClient
-	Collection string
+	Client       *solr.JSONClient
+	Collection   string
+	configSet    string
+	commitWithin time.Duration
+	baseURL      string
+	httpClient   *http.Client
 }
 
 // NewSolr initializes a new Solr client
@@ -28,145 +49,833 @@ func NewSolr(config SolrConfig) Solr {
 	client := solr.NewJSONClient(baseURL)
 
 	return Solr{
-		Client:     client,
-		Collection: config.Collection,
+		Client:       client,
+		Collection:   config.Collection,
+		configSet:    config.ConfigSet,
+		commitWithin: config.CommitWithin,
+		baseURL:      baseURL,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
 	}
 }
 
-// Index adds a new course document to the Solr collection
-func (searchEngine Solr) Index(ctx context.Context, course courses.CourseUpdate) (string, error) {
-	// Prepare the document for SolR
-	doc := map[string]interface{}{
-		"id":          course.CourseID,
-		"name":        course.Name,
-		"category":    course.Category,
-		"description": course.Description,
+// document maps a course to its Solr document
+func document(course courses.CourseUpdate) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              course.CourseID,
+		"name":            course.Name,
+		"category":        course.Category,
+		"description":     course.Description,
+		"instructor_id":   course.InstructorID,
+		"rating":          course.Rating,
+		"duration":        course.Duration,
+		"image_id":        course.ImageID,
+		"capacity":        course.Capacity,
+		"comment_count":   course.CommentCount,
+		"created_at":      course.CreatedAt,
+		"seats_remaining": course.SeatsRemaining,
+		"version":         course.Version,
+		"seats_version":   course.SeatsVersion,
+		// Permite a la reconciliación detectar documentos desactualizados
+		"content_hash": course.ContentHash(),
+	}
+}
+
+// Apply adds or replaces the given courses and removes the given ids with a
+// single update request. Instead of a hard commit per call, changes become
+// visible through a soft commit within the configured commitWithin window.
+func (searchEngine Solr) Apply(ctx context.Context, adds []courses.CourseUpdate, deletes []int64) error {
+	command := make(map[string]interface{})
+	if len(adds) > 0 {
+		docs := make([]interface{}, 0, len(adds))
+		for _, course := range adds {
+			docs = append(docs, document(course))
+		}
+		command["add"] = docs
+	}
+	if len(deletes) > 0 {
+		command["delete"] = deletes
+	}
+	if len(command) == 0 {
+		return nil
+	}
+	if err := searchEngine.update(ctx, command); err != nil {
+		return fmt.Errorf("failed to apply updates: %w", err)
+	}
+	return nil
+}
+
+// SetSeats updates the seats remaining and their version of the given courses,
+// keyed by course ID, with an atomic update that leaves the rest of each
+// document untouched. Courses that are no longer indexed are not created again:
+// the update fails with a version conflict instead.
+func (searchEngine Solr) SetSeats(ctx context.Context, seats map[int64]courses.Seats) error {
+	if len(seats) == 0 {
+		return nil
 	}
+	docs := make([]interface{}, 0, len(seats))
+	for id, course := range seats {
+		docs = append(docs, map[string]interface{}{
+			"id":              id,
+			"seats_remaining": map[string]interface{}{"set": course.Remaining},
+			"seats_version":   map[string]interface{}{"set": course.Version},
+			// Un _version_ de 1 exige que el documento exista
+			"_version_": 1,
+		})
+	}
+	if err := searchEngine.update(ctx, map[string]interface{}{"add": docs}); err != nil {
+		return fmt.Errorf("failed to update seats remaining: %w", err)
+	}
+	return nil
+}
 
-	// Prepare the index request
-	indexRequest := map[string]interface{}{
-		"add": []interface{}{doc}, // Use "add" with a list of documents
+// update sends an update command to the collection. Changes become visible
+// through a soft commit within the configured commitWithin window.
+func (searchEngine Solr) update(ctx context.Context, command map[string]interface{}) error {
+	body, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("error marshaling update request: %w", err)
 	}
 
-	// Index the document in SolR
-	body, err := json.Marshal(indexRequest)
+	params := url.Values{}
+	params.Set("commitWithin", strconv.FormatInt(searchEngine.commitWithin.Milliseconds(), 10))
+	params.Set("wt", "json")
+	urlStr := fmt.Sprintf("%s/solr/%s/update?%s", searchEngine.baseURL, searchEngine.Collection, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("error marshaling course document: %w", err)
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := searchEngine.Client.Update(ctx, searchEngine.Collection, solr.JSON, bytes.NewReader(body))
+	httpResp, err := searchEngine.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error indexing course: %w", err)
+		return fmt.Errorf("error sending update request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp struct {
+		Error *solr.ResponseError `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("error decoding response (status %d): %w", httpResp.StatusCode, err)
 	}
 	if resp.Error != nil {
-		return "", fmt.Errorf("failed to index course: %v", resp.Error)
+		return fmt.Errorf("%v", resp.Error)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
 	}
+	return nil
+}
 
-	// Commit the changes
-	if err := searchEngine.Client.Commit(ctx, searchEngine.Collection); err != nil {
-		return "", fmt.Errorf("error committing changes to SolR: %w", err)
+// IndexBatch adds or replaces several course documents in the given collection
+// with a single update request. Changes are not visible until Commit is called.
+func (searchEngine Solr) IndexBatch(ctx context.Context, collection string, batch []courses.CourseUpdate) error {
+	docs := make([]interface{}, 0, len(batch))
+	for _, course := range batch {
+		docs = append(docs, document(course))
 	}
 
-	return fmt.Sprintf("%d", course.CourseID), nil // Convert CourseID to string
-}
+	body, err := json.Marshal(map[string]interface{}{"add": docs})
+	if err != nil {
+		return fmt.Errorf("error marshaling course documents: %w", err)
+	}
 
-// Update modifies an existing course document in the Solr collection
-func (searchEngine Solr) Update(ctx context.Context, course courses.CourseUpdate) error {
-	// Prepare the document for Solr
-	doc := map[string]interface{}{
-		"id":          course.CourseID,
-		"name":        course.Name,
-		"category":    course.Category,
-		"description": course.Description,
+	resp, err := searchEngine.Client.Update(ctx, collection, solr.JSON, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error indexing courses: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to index courses: %v", resp.Error)
 	}
+	return nil
+}
 
-	// Prepare the update request
-	updateRequest := map[string]interface{}{
-		"add": []interface{}{doc}, // Use "add" with a list of documents
+// DeleteBatch removes several course documents from the given collection with a
+// single update request. Changes are not visible until Commit is called.
+func (searchEngine Solr) DeleteBatch(ctx context.Context, collection string, ids []int64) error {
+	body, err := json.Marshal(map[string]interface{}{"delete": ids})
+	if err != nil {
+		return fmt.Errorf("error marshaling course ids: %w", err)
 	}
 
-	// Update the document in Solr
-	body, err := json.Marshal(updateRequest)
+	resp, err := searchEngine.Client.Update(ctx, collection, solr.JSON, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("error marshaling course document: %w", err)
+		return fmt.Errorf("error deleting courses: %w", err)
 	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to delete courses: %v", resp.Error)
+	}
+	return nil
+}
+
+// IndexedCourses returns the content hash, seats remaining and seats version of
+// every indexed course, keyed by course ID
+func (searchEngine Solr) IndexedCourses(ctx context.Context) (map[int64]courses.IndexedCourse, error) {
+	const pageSize = 1000
+	indexed := make(map[int64]courses.IndexedCourse)
+	for offset := 0; ; offset += pageSize {
+		query := solr.NewQuery("*:*").
+			Fields("id", "content_hash", "seats_remaining", "seats_version").
+			Sort("id asc").
+			Offset(offset).
+			Limit(pageSize)
 
-	// Execute the update request
-	resp, err := searchEngine.Client.Update(ctx, searchEngine.Collection, solr.JSON, bytes.NewReader(body))
+		resp, err := searchEngine.Client.Query(ctx, searchEngine.Collection, query)
+		if err != nil {
+			return nil, fmt.Errorf("error listing indexed courses: %w", err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("failed to list indexed courses: %v", resp.Error)
+		}
+
+		for _, doc := range resp.Response.Documents {
+			indexed[getIntField(doc, "id")] = courses.IndexedCourse{
+				ContentHash:    getStringField(doc, "content_hash"),
+				SeatsRemaining: int(getIntField(doc, "seats_remaining")),
+				SeatsVersion:   getIntField(doc, "seats_version"),
+			}
+		}
+		if len(resp.Response.Documents) < pageSize {
+			return indexed, nil
+		}
+	}
+}
+
+// Versions returns the indexed course and seats versions of the given courses,
+// keyed by course ID. Courses that are not indexed are left out.
+func (searchEngine Solr) Versions(ctx context.Context, ids []int64) (map[int64]courses.IndexedVersion, error) {
+	versions := make(map[int64]courses.IndexedVersion, len(ids))
+	if len(ids) == 0 {
+		return versions, nil
+	}
+	terms := make([]string, 0, len(ids))
+	for _, id := range ids {
+		terms = append(terms, strconv.FormatInt(id, 10))
+	}
+	query := solr.NewQuery("id:("+strings.Join(terms, " OR ")+")").
+		Fields("id", "version", "seats_version").
+		Limit(len(ids))
+
+	resp, err := searchEngine.Client.Query(ctx, searchEngine.Collection, query)
 	if err != nil {
-		return fmt.Errorf("error updating course: %w", err)
+		return nil, fmt.Errorf("error reading indexed versions: %w", err)
 	}
 	if resp.Error != nil {
-		return fmt.Errorf("failed to update course: %v", resp.Error)
+		return nil, fmt.Errorf("failed to read indexed versions: %v", resp.Error)
 	}
+	for _, doc := range resp.Response.Documents {
+		versions[getIntField(doc, "id")] = courses.IndexedVersion{
+			Course: getIntField(doc, "version"),
+			Seats:  getIntField(doc, "seats_version"),
+		}
+	}
+	return versions, nil
+}
 
-	// Commit the changes
-	if err := searchEngine.Client.Commit(ctx, searchEngine.Collection); err != nil {
+// Commit makes the pending changes of the collection visible to searches
+func (searchEngine Solr) Commit(ctx context.Context, collection string) error {
+	if err := searchEngine.Client.Commit(ctx, collection); err != nil {
 		return fmt.Errorf("error committing changes to Solr: %w", err)
 	}
+	return nil
+}
 
+// CreateCollection creates a new collection using the configured configset.
+// Requires Solr running in SolrCloud mode.
+func (searchEngine Solr) CreateCollection(ctx context.Context, name string) error {
+	params := url.Values{}
+	params.Set("action", "CREATE")
+	params.Set("name", name)
+	params.Set("numShards", "1")
+	params.Set("collection.configName", searchEngine.configSet)
+	if _, err := searchEngine.collectionsAPI(ctx, params); err != nil {
+		return fmt.Errorf("error creating collection %s: %w", name, err)
+	}
 	return nil
 }
 
-// Delete removes a course document from the Solr collection
-func (searchEngine Solr) Delete(ctx context.Context, id string) error {
-	// Prepare the delete request
-	docToDelete := map[string]interface{}{
-		"delete": map[string]interface{}{
-			"id": id,
-		},
+// SwapAlias points the configured collection alias to the given collection and
+// returns the collection it pointed to before, if any. Requires SolrCloud mode.
+func (searchEngine Solr) SwapAlias(ctx context.Context, collection string) (string, error) {
+	params := url.Values{}
+	params.Set("action", "LISTALIASES")
+	resp, err := searchEngine.collectionsAPI(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("error listing aliases: %w", err)
 	}
+	previous := resp.Aliases[searchEngine.Collection]
+
+	params = url.Values{}
+	params.Set("action", "CREATEALIAS")
+	params.Set("name", searchEngine.Collection)
+	params.Set("collections", collection)
+	if _, err := searchEngine.collectionsAPI(ctx, params); err != nil {
+		return "", fmt.Errorf("error pointing alias %s to %s: %w", searchEngine.Collection, collection, err)
+	}
+	return previous, nil
+}
+
+// DeleteCollection removes a collection. Requires SolrCloud mode.
+func (searchEngine Solr) DeleteCollection(ctx context.Context, name string) error {
+	params := url.Values{}
+	params.Set("action", "DELETE")
+	params.Set("name", name)
+	if _, err := searchEngine.collectionsAPI(ctx, params); err != nil {
+		return fmt.Errorf("error deleting collection %s: %w", name, err)
+	}
+	return nil
+}
 
-	// Execute the delete request
-	body, err := json.Marshal(docToDelete)
+// Alias returns the collection name (or alias) searches are run against
+func (searchEngine Solr) Alias() string {
+	return searchEngine.Collection
+}
+
+// collectionsResponse is the subset of the Collections API response that is used
+type collectionsResponse struct {
+	Error   *solr.ResponseError `json:"error,omitempty"`
+	Aliases map[string]string   `json:"aliases,omitempty"`
+}
+
+// collectionsAPI sends a request to the Solr Collections API
+func (searchEngine Solr) collectionsAPI(ctx context.Context, params url.Values) (collectionsResponse, error) {
+	params.Set("wt", "json")
+	urlStr := fmt.Sprintf("%s/solr/admin/collections?%s", searchEngine.baseURL, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return fmt.Errorf("error marshaling course document: %w", err)
+		return collectionsResponse{}, err
 	}
 
-	// Execute the delete request
-	resp, err := searchEngine.Client.Update(ctx, searchEngine.Collection, solr.JSON, bytes.NewReader(body))
+	httpResp, err := searchEngine.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("error deleting course: %w", err)
+		return collectionsResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp collectionsResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return collectionsResponse{}, fmt.Errorf("error decoding response (status %d): %w", httpResp.StatusCode, err)
 	}
 	if resp.Error != nil {
-		return fmt.Errorf("failed to delete course: %v", resp.Error)
+		return collectionsResponse{}, resp.Error
 	}
+	if httpResp.StatusCode != http.StatusOK {
+		return collectionsResponse{}, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	return resp, nil
+}
 
-	// Commit the changes
-	if err := searchEngine.Client.Commit(ctx, searchEngine.Collection); err != nil {
-		return fmt.Errorf("error committing changes to Solr: %w", err)
+// Relevance weights used by edismax: a match in the name counts more than one in
+// the category, which counts more than one in the description. The stemmed
+// Spanish and English copies also match other forms of a word, weighing less
+// than the exact word.
+const (
+	queryFields  = "name^3 name_es^2 name_en^2 category^2 description description_es^0.5 description_en^0.5"
+	phraseFields = "name^5 description^2"
+	// All terms are required for short queries, longer ones may miss a few
+	minimumMatch = "2<-1 5<80%"
+)
+
+// Rating buckets offered as a facet, each one counts the courses rated at least that
+var ratingBuckets = []float64{4.5, 4, 3.5, 3}
+
+// Maximum number of values returned for each terms facet
+const facetLimit = 50
+
+// Tags of the facet filters, excluded when counting their own facet
+const (
+	categoryTag   = "category"
+	instructorTag = "instructor"
+	ratingTag     = "rating"
+	durationTag   = "duration"
+)
+
+// selectResponse is the subset of the /select response that is used
+type selectResponse struct {
+	Error    *solr.ResponseError `json:"error,omitempty"`
+	Response struct {
+		NumFound  int64                    `json:"numFound"`
+		Start     int64                    `json:"start"`
+		Documents []map[string]interface{} `json:"docs"`
+	} `json:"response"`
+	NextCursorMark string                         `json:"nextCursorMark,omitempty"`
+	Facets         map[string]json.RawMessage     `json:"facets,omitempty"`
+	Highlighting   map[string]map[string][]string `json:"highlighting,omitempty"`
+	Spellcheck     struct {
+		// Flat list alternating the "collation" key and the corrected query
+		Collations []interface{} `json:"collations"`
+	} `json:"spellcheck"`
+}
+
+// suggestResponse is the subset of the /suggest response that is used, keyed
+// by suggester and then by the suggested prefix
+type suggestResponse struct {
+	Suggest map[string]map[string]struct {
+		Suggestions []struct {
+			Term string `json:"term"`
+		} `json:"suggestions"`
+	} `json:"suggest"`
+}
+
+// Suggesters configured in solrconfig.xml and the kind of suggestion each one returns
+var suggesters = []struct {
+	name string
+	kind string
+}{
+	{name: "nameSuggester", kind: courses.SuggestionName},
+	{name: "categorySuggester", kind: courses.SuggestionCategory},
+}
+
+// facetResult is a JSON Facet API result, either a bucket list or a plain count
+type facetResult struct {
+	Count   int64 `json:"count"`
+	Buckets []struct {
+		Value interface{} `json:"val"`
+		Count int64       `json:"count"`
+	} `json:"buckets"`
+}
+
+// requestHandler sends a request to a request handler of the collection (select,
+// suggest...) and decodes the response into out. Unlike the JSON client it
+// accepts any request parameter (defType, qf, mm...).
+func (searchEngine Solr) requestHandler(ctx context.Context, handler string, params url.Values, out interface{}) error {
+	params.Set("wt", "json")
+	urlStr := fmt.Sprintf("%s/solr/%s/%s", searchEngine.baseURL, searchEngine.Collection, handler)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+	httpResp, err := searchEngine.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	var errResp struct {
+		Error *solr.ResponseError `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return fmt.Errorf("error decoding response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if errResp.Error != nil {
+		return errResp.Error
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
 	return nil
 }
 
-// Search searches for courses in the Solr collection based on a query
-func (searchEngine Solr) Search(ctx context.Context, query string, limit int, offset int) ([]courses.CourseUpdate, error) {
-	// Prepare the Solr query with limit and offset
-	solrQuery := fmt.Sprintf("q=(name:%s OR description:%s)&rows=%d&start=%d&wt=json", query, query, limit, offset)
+// Rating-boosted relevance multiplies the score by up to 2 for a 5 star course
+const ratingBoost = "sum(1,div(rating,5))"
 
-	// Execute the search request
-	resp, err := searchEngine.Client.Query(ctx, searchEngine.Collection, solr.NewQuery(solrQuery))
+// sortParams sets the ordering of the results. The course id breaks ties so
+// pages do not overlap.
+func sortParams(params url.Values, sort string) {
+	switch sort {
+	case courses.SortRelevance:
+		params.Set("sort", "score desc,id asc")
+	case courses.SortRating:
+		params.Set("sort", "rating desc,score desc,id asc")
+	case courses.SortNewest:
+		params.Set("sort", "created_at desc,id asc")
+	case courses.SortName:
+		params.Set("sort", "name_sort asc,id asc")
+	default:
+		params.Set("boost", ratingBoost)
+		params.Set("sort", "score desc,id asc")
+	}
+}
+
+// highlightParams asks for a highlighted snippet of name and description
+func highlightParams(params url.Values) {
+	params.Set("hl", "true")
+	params.Set("hl.method", "unified")
+	params.Set("hl.fl", "name,description")
+	params.Set("hl.snippets", "1")
+	params.Set("hl.fragsize", "150")
+	params.Set("hl.tag.pre", "<em>")
+	params.Set("hl.tag.post", "</em>")
+}
+
+// spellcheckParams asks for a "did you mean" correction of the words typed by
+// the user, leaving out the exclusions and the category filter
+func spellcheckParams(params url.Values, query courses.SearchQuery) {
+	words := append(append([]string{}, query.Terms...), query.Phrases...)
+	if len(words) == 0 {
+		return
+	}
+	params.Set("spellcheck", "true")
+	params.Set("spellcheck.q", strings.Join(words, " "))
+	params.Set("spellcheck.collate", "true")
+	// The correction is only offered if it finds results with the same filters
+	params.Set("spellcheck.maxCollationTries", "5")
+	params.Set("spellcheck.maxCollations", "1")
+}
+
+// didYouMean reads the first collation of a /select response
+func didYouMean(resp selectResponse) string {
+	collations := resp.Spellcheck.Collations
+	for i := 0; i+1 < len(collations); i += 2 {
+		if key, _ := collations[i].(string); key == "collation" {
+			if collation, ok := collations[i+1].(string); ok {
+				return collation
+			}
+		}
+	}
+	return ""
+}
+
+// searchParams translates a parsed query into edismax parameters. Every value
+// coming from the user is escaped, so it can never be read as Solr syntax.
+func searchParams(query courses.SearchQuery) url.Values {
+	clauses := make([]string, 0, len(query.Terms)+len(query.Phrases)+len(query.Excluded))
+	for _, term := range query.Terms {
+		clauses = append(clauses, escapeTerm(term))
+	}
+	for _, phrase := range query.Phrases {
+		clauses = append(clauses, quote(phrase))
+	}
+	for _, excluded := range query.Excluded {
+		clauses = append(clauses, "-"+quote(excluded))
+	}
+
+	params := url.Values{}
+	params.Set("defType", "edismax")
+	params.Set("q", strings.Join(clauses, " "))
+	// Without terms (e.g. only a category filter) every course matches
+	params.Set("q.alt", "*:*")
+	params.Set("qf", queryFields)
+	params.Set("pf", phraseFields)
+	params.Set("mm", minimumMatch)
+	// Only the explicit fields may be searched, "field:value" in q is ignored
+	params.Set("uf", "-*")
+	for _, category := range query.Categories {
+		params.Add("fq", "category:"+quote(category))
+	}
+	return params
+}
+
+// filterParams adds a tagged filter query for each facet filter. Values from
+// the same facet are ORed, different facets are ANDed.
+func filterParams(params url.Values, filters courses.SearchFilters) {
+	if len(filters.Categories) > 0 {
+		values := make([]string, 0, len(filters.Categories))
+		for _, category := range filters.Categories {
+			values = append(values, quote(category))
+		}
+		params.Add("fq", fmt.Sprintf("{!tag=%s}category_exact:(%s)", categoryTag, strings.Join(values, " OR ")))
+	}
+	if len(filters.InstructorIDs) > 0 {
+		values := make([]string, 0, len(filters.InstructorIDs))
+		for _, id := range filters.InstructorIDs {
+			values = append(values, strconv.FormatInt(id, 10))
+		}
+		params.Add("fq", fmt.Sprintf("{!tag=%s}instructor_id:(%s)", instructorTag, strings.Join(values, " OR ")))
+	}
+	if filters.MinRating > 0 {
+		params.Add("fq", fmt.Sprintf("{!tag=%s}rating:[%s TO *]", ratingTag, strconv.FormatFloat(filters.MinRating, 'f', -1, 64)))
+	}
+	if len(filters.Durations) > 0 {
+		values := make([]string, 0, len(filters.Durations))
+		for _, duration := range filters.Durations {
+			values = append(values, quote(duration))
+		}
+		params.Add("fq", fmt.Sprintf("{!tag=%s}duration:(%s)", durationTag, strings.Join(values, " OR ")))
+	}
+}
+
+// facetParams builds the json.facet parameter for the category, instructor,
+// rating and duration facets
+func facetParams() (string, error) {
+	termsFacet := func(field string, tag string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":   "terms",
+			"field":  field,
+			"limit":  facetLimit,
+			"domain": map[string]interface{}{"excludeTags": tag},
+		}
+	}
+	facets := map[string]interface{}{
+		"categories":  termsFacet("category_exact", categoryTag),
+		"instructors": termsFacet("instructor_id", instructorTag),
+		"durations":   termsFacet("duration", durationTag),
+	}
+	for _, bucket := range ratingBuckets {
+		facets[ratingFacetName(bucket)] = map[string]interface{}{
+			"type":   "query",
+			"q":      fmt.Sprintf("rating:[%s TO *]", strconv.FormatFloat(bucket, 'f', -1, 64)),
+			"domain": map[string]interface{}{"excludeTags": ratingTag},
+		}
+	}
+
+	bytes, err := json.Marshal(facets)
 	if err != nil {
-		return nil, fmt.Errorf("error executing search query: %w", err)
+		return "", err
 	}
-	if resp.Error != nil {
-		return nil, fmt.Errorf("failed to execute search query: %v", resp.Error)
+	return string(bytes), nil
+}
+
+func ratingFacetName(bucket float64) string {
+	return "rating_" + strconv.FormatFloat(bucket, 'f', -1, 64)
+}
+
+// parseFacets reads the facet counts of a /select response
+func parseFacets(raw map[string]json.RawMessage) (courses.Facets, error) {
+	facets := courses.Facets{
+		Categories:  []courses.FacetCount{},
+		Instructors: []courses.FacetCount{},
+		Ratings:     []courses.FacetCount{},
+		Durations:   []courses.FacetCount{},
+	}
+	buckets := func(name string, counts *[]courses.FacetCount) error {
+		result, ok := raw[name]
+		if !ok {
+			return nil
+		}
+		var facet facetResult
+		if err := json.Unmarshal(result, &facet); err != nil {
+			return fmt.Errorf("error decoding facet %s: %w", name, err)
+		}
+		for _, bucket := range facet.Buckets {
+			value := fmt.Sprint(bucket.Value)
+			// Numeric values are decoded as float64, 7 must not be rendered as 7e+00
+			if number, ok := bucket.Value.(float64); ok {
+				value = strconv.FormatFloat(number, 'f', -1, 64)
+			}
+			*counts = append(*counts, courses.FacetCount{Value: value, Count: bucket.Count})
+		}
+		return nil
+	}
+	if err := buckets("categories", &facets.Categories); err != nil {
+		return courses.Facets{}, err
+	}
+	if err := buckets("instructors", &facets.Instructors); err != nil {
+		return courses.Facets{}, err
+	}
+	if err := buckets("durations", &facets.Durations); err != nil {
+		return courses.Facets{}, err
+	}
+
+	for _, bucket := range ratingBuckets {
+		result, ok := raw[ratingFacetName(bucket)]
+		if !ok {
+			continue
+		}
+		var facet facetResult
+		if err := json.Unmarshal(result, &facet); err != nil {
+			return courses.Facets{}, fmt.Errorf("error decoding rating facet: %w", err)
+		}
+		facets.Ratings = append(facets.Ratings, courses.FacetCount{
+			Value: strconv.FormatFloat(bucket, 'f', -1, 64),
+			Count: facet.Count,
+		})
+	}
+	return facets, nil
+}
+
+// escapeTerm escapes the characters with a special meaning in the Solr query
+// syntax. Boolean operators are quoted so they are searched as words.
+func escapeTerm(term string) string {
+	switch term {
+	case "AND", "OR", "NOT":
+		return quote(term)
+	}
+	var escaped strings.Builder
+	for _, r := range term {
+		if strings.ContainsRune(`\+-!():^[]"{}~*?|&/`, r) || unicode.IsSpace(r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// quote builds a phrase query, escaping quotes and backslashes inside it
+func quote(phrase string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(phrase) + `"`
+}
+
+// Search searches for courses in the Solr collection using edismax, applying
+// the facet filters and returning the facet counts along with the total hits.
+// When the page has a cursor, deep paging uses cursorMark instead of start,
+// which works because every sort ends with the unique key.
+func (searchEngine Solr) Search(ctx context.Context, query courses.SearchQuery, filters courses.SearchFilters, sort string, page courses.SearchPage) (courses.SearchResponse, error) {
+	params := searchParams(query)
+	filterParams(params, filters)
+	facets, err := facetParams()
+	if err != nil {
+		return courses.SearchResponse{}, fmt.Errorf("error building facets: %w", err)
+	}
+	params.Set("json.facet", facets)
+	params.Set("fl", resultFields+",score")
+	sortParams(params, sort)
+	highlightParams(params)
+	params.Set("rows", strconv.Itoa(page.Limit))
+	if page.Cursor != "" {
+		params.Set("cursorMark", page.Cursor)
+	} else {
+		params.Set("start", strconv.Itoa(page.Offset))
+	}
+	spellcheckParams(params, query)
+
+	// Execute the search request
+	var resp selectResponse
+	if err := searchEngine.requestHandler(ctx, "select", params, &resp); err != nil {
+		var solrErr *solr.ResponseError
+		if page.Cursor != "" && errors.As(err, &solrErr) && solrErr.Code == http.StatusBadRequest {
+			return courses.SearchResponse{}, fmt.Errorf("%w: %s", ErrInvalidCursor, solrErr.Msg)
+		}
+		return courses.SearchResponse{}, fmt.Errorf("error executing search query: %w", err)
 	}
 
 	// Parse the response and extract course documents
-	var coursesList []courses.CourseUpdate
+	hits := make([]courses.SearchHit, 0, len(resp.Response.Documents))
+	for _, doc := range resp.Response.Documents {
+		hit := courses.SearchHit{
+			CourseUpdate: fromDocument(doc),
+			Score:        getFloatField(doc, "score"),
+		}
+		// Highlighting is keyed by the unique key; fields without a match come back empty
+		for field, snippets := range resp.Highlighting[strconv.FormatInt(hit.CourseID, 10)] {
+			if len(snippets) == 0 {
+				continue
+			}
+			if hit.Highlights == nil {
+				hit.Highlights = make(map[string][]string)
+			}
+			hit.Highlights[field] = snippets
+		}
+		hits = append(hits, hit)
+	}
+
+	facetCounts, err := parseFacets(resp.Facets)
+	if err != nil {
+		return courses.SearchResponse{}, err
+	}
+
+	response := courses.SearchResponse{
+		Total:      resp.Response.NumFound,
+		Offset:     page.Offset,
+		Limit:      page.Limit,
+		Results:    hits,
+		Facets:     facetCounts,
+		DidYouMean: didYouMean(resp),
+	}
+	// Solr returns the same cursor once there are no more results
+	if page.Cursor != "" && resp.NextCursorMark != page.Cursor {
+		response.NextCursor = resp.NextCursorMark
+	}
+	return response, nil
+}
+
+// Fields compared by MoreLikeThis to find similar courses
+const similarFields = "name,description,category"
+
+// Similar returns the courses most similar to the given one using MoreLikeThis,
+// leaving out the course itself and the courses without seats remaining
+func (searchEngine Solr) Similar(ctx context.Context, courseID int64, limit int) (courses.SimilarCoursesResponse, error) {
+	id := strconv.FormatInt(courseID, 10)
+
+	// The MLT parser fails with a generic error when the course does not exist
+	exists := url.Values{}
+	exists.Set("q", "id:"+id)
+	exists.Set("rows", "0")
+	var found selectResponse
+	if err := searchEngine.requestHandler(ctx, "select", exists, &found); err != nil {
+		return courses.SimilarCoursesResponse{}, fmt.Errorf("error looking up course %s: %w", id, err)
+	}
+	if found.Response.NumFound == 0 {
+		return courses.SimilarCoursesResponse{}, ErrCourseNotFound
+	}
+
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("{!mlt qf=%s mintf=1 mindf=1}%s", similarFields, id))
+	params.Add("fq", "-id:"+id)
+	params.Add("fq", "seats_remaining:[1 TO *]")
+	params.Set("fl", resultFields+",score")
+	params.Set("rows", strconv.Itoa(limit))
+
+	var resp selectResponse
+	if err := searchEngine.requestHandler(ctx, "select", params, &resp); err != nil {
+		return courses.SimilarCoursesResponse{}, fmt.Errorf("error executing more like this query: %w", err)
+	}
+
+	hits := make([]courses.SearchHit, 0, len(resp.Response.Documents))
 	for _, doc := range resp.Response.Documents {
-		course := courses.CourseUpdate{
-			CourseID:    getIntField(doc, "id"),
-			Name:        getStringField(doc, "name"),
-			Category:    getStringField(doc, "category"),
-			Description: getStringField(doc, "description"),
+		hits = append(hits, courses.SearchHit{
+			CourseUpdate: fromDocument(doc),
+			Score:        getFloatField(doc, "score"),
+		})
+	}
+	return courses.SimilarCoursesResponse{
+		Total:   resp.Response.NumFound,
+		Results: hits,
+	}, nil
+}
+
+// Suggest returns course names and categories that complete the given prefix
+func (searchEngine Solr) Suggest(ctx context.Context, prefix string, limit int) ([]courses.Suggestion, error) {
+	params := url.Values{}
+	params.Set("suggest.q", prefix)
+	params.Set("suggest.count", strconv.Itoa(limit))
+
+	var resp suggestResponse
+	if err := searchEngine.requestHandler(ctx, "suggest", params, &resp); err != nil {
+		return nil, fmt.Errorf("error executing suggest query: %w", err)
+	}
+
+	// Several courses share the same category, each suggestion is returned once
+	seen := make(map[string]bool)
+	suggestions := make([]courses.Suggestion, 0)
+	for _, suggester := range suggesters {
+		for _, result := range resp.Suggest[suggester.name] {
+			for _, suggestion := range result.Suggestions {
+				key := suggester.kind + ":" + strings.ToLower(suggestion.Term)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				suggestions = append(suggestions, courses.Suggestion{Text: suggestion.Term, Type: suggester.kind})
+			}
 		}
-		coursesList = append(coursesList, course)
 	}
+	// Each suggester returns up to limit suggestions, names are kept first
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// Stored fields returned for each search result, the full course card
+const resultFields = "id,name,category,description,instructor_id,rating,duration,image_id,capacity,comment_count,created_at,seats_remaining,version"
 
-	return coursesList, nil
+// fromDocument maps a Solr document back to a course
+func fromDocument(doc map[string]interface{}) courses.CourseUpdate {
+	return courses.CourseUpdate{
+		CourseID:       getIntField(doc, "id"),
+		Name:           getStringField(doc, "name"),
+		Category:       getStringField(doc, "category"),
+		Description:    getStringField(doc, "description"),
+		InstructorID:   getIntField(doc, "instructor_id"),
+		Rating:         getFloatField(doc, "rating"),
+		Duration:       getStringField(doc, "duration"),
+		ImageID:        getStringField(doc, "image_id"),
+		Capacity:       int(getIntField(doc, "capacity")),
+		CommentCount:   int(getIntField(doc, "comment_count")),
+		CreatedAt:      getIntField(doc, "created_at"),
+		SeatsRemaining: int(getIntField(doc, "seats_remaining")),
+		Version:        getIntField(doc, "version"),
+	}
 }
 
 // Helper function to safely get string fields from the document
@@ -189,3 +898,11 @@ func getIntField(doc map[string]interface{}, field string) int64 {
 	}
 	return 0
 }
+
+// Helper function to safely get float64 fields from the document
+func getFloatField(doc map[string]interface{}, field string) float64 {
+	if val, ok := doc[field].(float64); ok {
+		return val
+	}
+	return 0
+}
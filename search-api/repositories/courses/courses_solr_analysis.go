@@ -0,0 +1,203 @@
+package courses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/stevenferrer/solr-go"
+)
+
+// ErrTermNotFound is returned when removing a synonym or stopword that is not in the list
+var ErrTermNotFound = errors.New("term not found in the managed list")
+
+// Name of the managed synonym and stopword resources used by the schema
+const managedResource = "courses"
+
+// Paths of the managed resources, relative to the collection
+const (
+	synonymsPath  = "schema/analysis/synonyms/" + managedResource
+	stopwordsPath = "schema/analysis/stopwords/" + managedResource
+)
+
+// Synonyms returns the synonym mappings, each term expands to its synonyms at query time
+func (searchEngine Solr) Synonyms(ctx context.Context) (map[string][]string, error) {
+	var resp struct {
+		SynonymMappings struct {
+			ManagedMap map[string][]string `json:"managedMap"`
+		} `json:"synonymMappings"`
+	}
+	if err := searchEngine.restRequest(ctx, http.MethodGet, synonymsPath, nil, &resp); err != nil {
+		return nil, fmt.Errorf("error getting synonyms: %w", err)
+	}
+	if resp.SynonymMappings.ManagedMap == nil {
+		return map[string][]string{}, nil
+	}
+	return resp.SynonymMappings.ManagedMap, nil
+}
+
+// AddSynonyms adds or replaces synonym mappings. They are applied after Reload.
+func (searchEngine Solr) AddSynonyms(ctx context.Context, mappings map[string][]string) error {
+	if err := searchEngine.restRequest(ctx, http.MethodPut, synonymsPath, mappings, nil); err != nil {
+		return fmt.Errorf("error adding synonyms: %w", err)
+	}
+	return nil
+}
+
+// DeleteSynonym removes the mapping of a term. The change is applied after Reload.
+func (searchEngine Solr) DeleteSynonym(ctx context.Context, term string) error {
+	if err := searchEngine.restRequest(ctx, http.MethodDelete, synonymsPath+"/"+url.PathEscape(term), nil, nil); err != nil {
+		return fmt.Errorf("error deleting synonym %q: %w", term, err)
+	}
+	return nil
+}
+
+// Stopwords returns the words ignored in search queries
+func (searchEngine Solr) Stopwords(ctx context.Context) ([]string, error) {
+	var resp struct {
+		WordSet struct {
+			ManagedList []string `json:"managedList"`
+		} `json:"wordSet"`
+	}
+	if err := searchEngine.restRequest(ctx, http.MethodGet, stopwordsPath, nil, &resp); err != nil {
+		return nil, fmt.Errorf("error getting stopwords: %w", err)
+	}
+	if resp.WordSet.ManagedList == nil {
+		return []string{}, nil
+	}
+	return resp.WordSet.ManagedList, nil
+}
+
+// AddStopwords adds words to the stopword list. They are applied after Reload.
+func (searchEngine Solr) AddStopwords(ctx context.Context, words []string) error {
+	if err := searchEngine.restRequest(ctx, http.MethodPut, stopwordsPath, words, nil); err != nil {
+		return fmt.Errorf("error adding stopwords: %w", err)
+	}
+	return nil
+}
+
+// DeleteStopword removes a word from the stopword list. The change is applied after Reload.
+func (searchEngine Solr) DeleteStopword(ctx context.Context, word string) error {
+	if err := searchEngine.restRequest(ctx, http.MethodDelete, stopwordsPath+"/"+url.PathEscape(word), nil, nil); err != nil {
+		return fmt.Errorf("error deleting stopword %q: %w", word, err)
+	}
+	return nil
+}
+
+// Reload reloads the collection so analyzers pick up the managed resources.
+// With SolrCloud the collection behind the alias is reloaded through the
+// Collections API; standalone Solr has no Collections API, so the core is
+// reloaded instead.
+func (searchEngine Solr) Reload(ctx context.Context) error {
+	params := url.Values{}
+	params.Set("action", "LISTALIASES")
+	aliases, err := searchEngine.collectionsAPI(ctx, params)
+	if err != nil {
+		return searchEngine.reloadCore(ctx)
+	}
+
+	collection := searchEngine.Collection
+	if target, ok := aliases.Aliases[collection]; ok {
+		collection = target
+	}
+	params = url.Values{}
+	params.Set("action", "RELOAD")
+	params.Set("name", collection)
+	if _, err := searchEngine.collectionsAPI(ctx, params); err != nil {
+		return fmt.Errorf("error reloading collection %s: %w", collection, err)
+	}
+	return nil
+}
+
+// reloadCore reloads the core named after the collection with the CoreAdmin API
+func (searchEngine Solr) reloadCore(ctx context.Context) error {
+	params := url.Values{}
+	params.Set("action", "RELOAD")
+	params.Set("core", searchEngine.Collection)
+	params.Set("wt", "json")
+	urlStr := fmt.Sprintf("%s/solr/admin/cores?%s", searchEngine.baseURL, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := searchEngine.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error reloading core %s: %w", searchEngine.Collection, err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp struct {
+		Error *solr.ResponseError `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("error decoding response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("error reloading core %s: %w", searchEngine.Collection, resp.Error)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error reloading core %s: unexpected status code %d", searchEngine.Collection, httpResp.StatusCode)
+	}
+	return nil
+}
+
+// restRequest sends a JSON request to a REST endpoint of the collection, such
+// as the managed resources. Deleting a term that does not exist returns ErrTermNotFound.
+func (searchEngine Solr) restRequest(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshaling request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	urlStr := fmt.Sprintf("%s/solr/%s/%s?wt=json", searchEngine.baseURL, searchEngine.Collection, path)
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := searchEngine.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusNotFound && method == http.MethodDelete {
+		return ErrTermNotFound
+	}
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	var errResp struct {
+		Error *solr.ResponseError `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(respBody, &errResp); err != nil {
+		return fmt.Errorf("error decoding response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if errResp.Error != nil {
+		return errResp.Error
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package inscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type HTTPConfig struct {
+	Host string
+	Port string
+}
+
+// HTTP cliente de la API de inscripciones, usado para calcular los cupos disponibles
+type HTTP struct {
+	courseURL func(courseID int64) string
+	client    *http.Client
+}
+
+func NewHTTP(config HTTPConfig) HTTP {
+	return HTTP{
+		courseURL: func(courseID int64) string {
+			// Solo las inscripciones pendientes y activas ocupan un cupo
+			return fmt.Sprintf("http://%s:%s/courses/%d/inscriptions?status=pending,active", config.Host, config.Port, courseID)
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CountByCourse devuelve la cantidad de inscripciones que ocupan un cupo del curso
+func (repository HTTP) CountByCourse(ctx context.Context, courseID int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, repository.courseURL(courseID), nil)
+	if err != nil {
+		return 0, fmt.Errorf("Error building inscriptions request (%d): %w", courseID, err)
+	}
+	resp, err := repository.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Error fetching inscriptions (%d): %w", courseID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Failed to fetch inscriptions (%d): received status code %d", courseID, resp.StatusCode)
+	}
+
+	var inscriptions []struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inscriptions); err != nil {
+		return 0, fmt.Errorf("Error unmarshaling inscriptions (%d): %w", courseID, err)
+	}
+	return len(inscriptions), nil
+}
@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	repo "search-api/repositories/courses"
+	"strings"
+)
+
+// ErrEmptyList se devuelve al agregar una lista de sinónimos o stopwords sin términos
+var ErrEmptyList = errors.New("la lista no tiene términos")
+
+// ErrTermNotFound se devuelve al quitar un sinónimo o stopword que no está en la lista
+var ErrTermNotFound = repo.ErrTermNotFound
+
+// ErrUnsupported se devuelve cuando el motor de búsqueda no administra sinónimos ni stopwords
+var ErrUnsupported = repo.ErrUnsupported
+
+// Repository define las operaciones sobre los sinónimos y stopwords administrados por SolR
+type Repository interface {
+	Synonyms(ctx context.Context) (map[string][]string, error)
+	AddSynonyms(ctx context.Context, mappings map[string][]string) error
+	DeleteSynonym(ctx context.Context, term string) error
+	Stopwords(ctx context.Context) ([]string, error)
+	AddStopwords(ctx context.Context, words []string) error
+	DeleteStopword(ctx context.Context, word string) error
+	Reload(ctx context.Context) error
+}
+
+// Service administra los sinónimos y stopwords que se aplican a las búsquedas.
+// Los cambios se aplican al recargar la colección, sin necesidad de reindexar.
+type Service struct {
+	repository Repository
+}
+
+// NewService crea una nueva instancia del servicio de análisis de búsquedas
+func NewService(repository Repository) Service {
+	return Service{
+		repository: repository,
+	}
+}
+
+// normalize pasa un término a minúsculas y quita los espacios de los extremos
+func normalize(term string) string {
+	return strings.ToLower(strings.TrimSpace(term))
+}
+
+// Synonyms devuelve los sinónimos: cada término se expande a su lista al buscar
+func (service Service) Synonyms(ctx context.Context) (map[string][]string, error) {
+	return service.repository.Synonyms(ctx)
+}
+
+// AddSynonyms agrega o reemplaza sinónimos, por ejemplo "js" -> ["javascript"].
+// Para que la equivalencia valga en ambos sentidos hay que agregar las dos entradas.
+func (service Service) AddSynonyms(ctx context.Context, mappings map[string][]string) error {
+	normalized := make(map[string][]string, len(mappings))
+	for term, synonyms := range mappings {
+		term = normalize(term)
+		if term == "" {
+			continue
+		}
+		for _, synonym := range synonyms {
+			if synonym = normalize(synonym); synonym != "" && synonym != term {
+				normalized[term] = append(normalized[term], synonym)
+			}
+		}
+	}
+	if len(normalized) == 0 {
+		return ErrEmptyList
+	}
+	return service.repository.AddSynonyms(ctx, normalized)
+}
+
+// DeleteSynonym quita los sinónimos de un término
+func (service Service) DeleteSynonym(ctx context.Context, term string) error {
+	return service.repository.DeleteSynonym(ctx, normalize(term))
+}
+
+// Stopwords devuelve las palabras que se ignoran en las búsquedas
+func (service Service) Stopwords(ctx context.Context) ([]string, error) {
+	return service.repository.Stopwords(ctx)
+}
+
+// AddStopwords agrega palabras a ignorar en las búsquedas
+func (service Service) AddStopwords(ctx context.Context, words []string) error {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = normalize(word); word != "" {
+			normalized = append(normalized, word)
+		}
+	}
+	if len(normalized) == 0 {
+		return ErrEmptyList
+	}
+	return service.repository.AddStopwords(ctx, normalized)
+}
+
+// DeleteStopword quita una palabra de las stopwords
+func (service Service) DeleteStopword(ctx context.Context, word string) error {
+	return service.repository.DeleteStopword(ctx, normalize(word))
+}
+
+// Reload recarga la colección para que las búsquedas usen los sinónimos y stopwords actuales
+func (service Service) Reload(ctx context.Context) error {
+	if err := service.repository.Reload(ctx); err != nil {
+		return fmt.Errorf("error al recargar la colección: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	domain "search-api/domain/analytics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidClick se devuelve cuando el clic no indica la búsqueda o el curso
+var ErrInvalidClick = errors.New("el clic debe indicar search_id y course_id")
+
+// ErrInvalidPeriod se devuelve cuando el período del informe excede la retención
+var ErrInvalidPeriod = errors.New("período de informe inválido")
+
+// Store define el almacenamiento local de las búsquedas y clics
+type Store interface {
+	Append(record domain.Record, timestamp time.Time) error
+	Load(since time.Time, fn func(domain.Record)) error
+	Prune(before time.Time) error
+}
+
+// queryCounters acumula las métricas de una consulta en un día
+type queryCounters struct {
+	searches        int64
+	zeroResults     int64
+	hits            int64
+	latencyMillis   float64
+	clicks          int64
+	clickedSearches int64
+}
+
+// dayStats métricas de un día, por consulta normalizada
+type dayStats struct {
+	queries map[string]*queryCounters
+	clicked map[string]bool // Búsquedas con al menos un clic, para no contarlas dos veces
+}
+
+// Service registra las búsquedas y clics y arma informes agregados por consulta.
+// Los eventos se guardan en el almacenamiento local y se agregan en memoria por
+// día, por lo que los informes no vuelven a leer los archivos.
+type Service struct {
+	store     Store
+	retention time.Duration
+	mu        *sync.Mutex
+	days      map[string]*dayStats
+}
+
+// NewService crea el servicio de analíticas, que conserva los datos durante retention
+func NewService(store Store, retention time.Duration) Service {
+	return Service{
+		store:     store,
+		retention: retention,
+		mu:        &sync.Mutex{},
+		days:      make(map[string]*dayStats),
+	}
+}
+
+// Load agrega los eventos guardados dentro del período de retención y borra los anteriores
+func (service Service) Load() error {
+	since := time.Now().Add(-service.retention)
+	if err := service.store.Prune(since); err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return service.store.Load(since, func(record domain.Record) {
+		if record.Search != nil {
+			service.addSearch(*record.Search)
+		}
+		if record.Click != nil {
+			service.addClick(*record.Click)
+		}
+	})
+}
+
+// normalizeQuery agrupa las consultas que solo difieren en mayúsculas o espacios
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// dayKey devuelve el día UTC de un instante
+func dayKey(timestamp time.Time) string {
+	return timestamp.UTC().Format("2006-01-02")
+}
+
+// counters devuelve las métricas de una consulta en un día. Debe llamarse con el lock tomado.
+func (service Service) counters(timestamp time.Time, query string) (*dayStats, *queryCounters) {
+	key := dayKey(timestamp)
+	day, ok := service.days[key]
+	if !ok {
+		day = &dayStats{queries: make(map[string]*queryCounters), clicked: make(map[string]bool)}
+		service.days[key] = day
+		service.expire(timestamp)
+	}
+	counters, ok := day.queries[query]
+	if !ok {
+		counters = &queryCounters{}
+		day.queries[query] = counters
+	}
+	return day, counters
+}
+
+// expire descarta de memoria los días fuera del período de retención. Debe llamarse con el lock tomado.
+func (service Service) expire(now time.Time) {
+	oldest := dayKey(now.Add(-service.retention))
+	for key := range service.days {
+		if key < oldest {
+			delete(service.days, key)
+		}
+	}
+}
+
+func (service Service) addSearch(event domain.SearchEvent) {
+	_, counters := service.counters(event.Timestamp, normalizeQuery(event.Query))
+	counters.searches++
+	counters.hits += event.Hits
+	counters.latencyMillis += event.LatencyMillis
+	if event.Hits == 0 {
+		counters.zeroResults++
+	}
+}
+
+func (service Service) addClick(event domain.ClickEvent) {
+	day, counters := service.counters(event.Timestamp, normalizeQuery(event.Query))
+	counters.clicks++
+	if !day.clicked[event.SearchID] {
+		day.clicked[event.SearchID] = true
+		counters.clickedSearches++
+	}
+}
+
+// RecordSearch registra una búsqueda. Un error al guardarla solo se informa en el
+// log, las analíticas no deben hacer fallar la búsqueda.
+func (service Service) RecordSearch(event domain.SearchEvent) {
+	service.record(domain.Record{Search: &event}, event.Timestamp)
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.addSearch(event)
+}
+
+// RecordClick registra el clic de un usuario en un resultado de búsqueda
+func (service Service) RecordClick(event domain.ClickEvent) error {
+	if event.SearchID == "" || event.CourseID <= 0 {
+		return ErrInvalidClick
+	}
+	event.Timestamp = time.Now()
+	service.record(domain.Record{Click: &event}, event.Timestamp)
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	service.addClick(event)
+	return nil
+}
+
+// record guarda un evento y, al empezar un día nuevo, borra los archivos vencidos
+func (service Service) record(record domain.Record, timestamp time.Time) {
+	if err := service.store.Append(record, timestamp); err != nil {
+		log.Printf("Error al guardar el evento de analíticas: %v", err)
+	}
+	service.mu.Lock()
+	_, known := service.days[dayKey(timestamp)]
+	service.mu.Unlock()
+	if !known {
+		if err := service.store.Prune(timestamp.Add(-service.retention)); err != nil {
+			log.Printf("Error al borrar las analíticas vencidas: %v", err)
+		}
+	}
+}
+
+// Report agrega los últimos days días: totales, las limit consultas más buscadas
+// y las limit consultas sin resultados más frecuentes, con su CTR
+func (service Service) Report(days int, limit int) (domain.Report, error) {
+	if maxDays := int(service.retention / (24 * time.Hour)); days < 1 || days > maxDays {
+		return domain.Report{}, fmt.Errorf("%w: debe estar entre 1 y %d días", ErrInvalidPeriod, maxDays)
+	}
+	to := time.Now().UTC()
+	from := to.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+
+	service.mu.Lock()
+	totals := make(map[string]*queryCounters)
+	for key, day := range service.days {
+		if key < dayKey(from) {
+			continue
+		}
+		for query, counters := range day.queries {
+			total, ok := totals[query]
+			if !ok {
+				total = &queryCounters{}
+				totals[query] = total
+			}
+			total.searches += counters.searches
+			total.zeroResults += counters.zeroResults
+			total.hits += counters.hits
+			total.latencyMillis += counters.latencyMillis
+			total.clicks += counters.clicks
+			total.clickedSearches += counters.clickedSearches
+		}
+	}
+	service.mu.Unlock()
+
+	report := domain.Report{From: from, To: to}
+	var overall queryCounters
+	var queries, zeroResultQueries []domain.QueryStats
+	for query, counters := range totals {
+		overall.searches += counters.searches
+		overall.zeroResults += counters.zeroResults
+		overall.latencyMillis += counters.latencyMillis
+		overall.clicks += counters.clicks
+		overall.clickedSearches += counters.clickedSearches
+		// Las búsquedas solo con filtros cuentan en los totales pero no son una consulta
+		if query == "" || counters.searches == 0 {
+			continue
+		}
+		stats := queryStats(query, counters)
+		queries = append(queries, stats)
+		if stats.ZeroResults > 0 {
+			zeroResultQueries = append(zeroResultQueries, stats)
+		}
+	}
+
+	report.Searches = overall.searches
+	report.ZeroResultSearches = overall.zeroResults
+	report.Clicks = overall.clicks
+	if overall.searches > 0 {
+		report.CTR = float64(overall.clickedSearches) / float64(overall.searches)
+		report.AvgLatencyMillis = overall.latencyMillis / float64(overall.searches)
+	}
+	report.TopQueries = top(queries, limit, func(stats domain.QueryStats) int64 { return stats.Searches })
+	report.ZeroResultQueries = top(zeroResultQueries, limit, func(stats domain.QueryStats) int64 { return stats.ZeroResults })
+	return report, nil
+}
+
+func queryStats(query string, counters *queryCounters) domain.QueryStats {
+	searches := float64(counters.searches)
+	return domain.QueryStats{
+		Query:            query,
+		Searches:         counters.searches,
+		ZeroResults:      counters.zeroResults,
+		Clicks:           counters.clicks,
+		ClickedSearches:  counters.clickedSearches,
+		CTR:              float64(counters.clickedSearches) / searches,
+		AvgHits:          float64(counters.hits) / searches,
+		AvgLatencyMillis: counters.latencyMillis / searches,
+	}
+}
+
+// top ordena las consultas por la métrica dada, de mayor a menor, y devuelve las primeras limit
+func top(stats []domain.QueryStats, limit int, metric func(domain.QueryStats) int64) []domain.QueryStats {
+	sort.Slice(stats, func(i, j int) bool {
+		if metric(stats[i]) != metric(stats[j]) {
+			return metric(stats[i]) > metric(stats[j])
+		}
+		return stats[i].Query < stats[j].Query
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	if stats == nil {
+		return []domain.QueryStats{}
+	}
+	return stats
+}
@@ -0,0 +1,246 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"log"
+	domain "search-api/domain/courses"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyRunning se devuelve al pedir una reconciliación mientras otra está en curso
+var ErrAlreadyRunning = errors.New("ya hay una reconciliación en curso")
+
+// Index define las operaciones necesarias sobre SolR para reparar el índice
+type Index interface {
+	IndexedCourses(ctx context.Context) (map[int64]domain.IndexedCourse, error)
+	IndexBatch(ctx context.Context, collection string, batch []domain.CourseUpdate) error
+	DeleteBatch(ctx context.Context, collection string, ids []int64) error
+	Commit(ctx context.Context, collection string) error
+	Alias() string
+}
+
+// CoursesAPI define las consultas a la API de cursos
+type CoursesAPI interface {
+	GetCoursesAfter(ctx context.Context, afterID int64, pageSize int) ([]domain.CourseUpdate, int64, error)
+	GetCourseByID(ctx context.Context, id string) (domain.CourseUpdate, error)
+}
+
+// Inscriptions define la consulta de inscripciones usada para calcular los cupos disponibles
+type Inscriptions interface {
+	CountByCourse(ctx context.Context, courseID int64) (int, error)
+}
+
+// Service compara periódicamente los cursos de la API de cursos con los documentos
+// de SolR y repara las diferencias que dejan los eventos perdidos
+type Service struct {
+	index        Index
+	coursesAPI   CoursesAPI
+	inscriptions Inscriptions
+	pageSize     int
+	running      *sync.Mutex
+	mu           *sync.Mutex
+	report       *domain.DriftReport
+}
+
+// NewService crea una nueva instancia del servicio de reconciliación
+func NewService(index Index, coursesAPI CoursesAPI, inscriptions Inscriptions, pageSize int) Service {
+	return Service{
+		index:        index,
+		coursesAPI:   coursesAPI,
+		inscriptions: inscriptions,
+		pageSize:     pageSize,
+		running:      &sync.Mutex{},
+		mu:           &sync.Mutex{},
+	}
+}
+
+// Start ejecuta la reconciliación cada interval hasta que se cancele el contexto
+func (service Service) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := service.Run(ctx)
+			if err != nil {
+				log.Printf("Error en la reconciliación: %v", err)
+				continue
+			}
+			if len(report.Missing)+len(report.Stale)+len(report.Seats)+len(report.Orphans) > 0 {
+				log.Printf("Reconciliación: %d faltantes, %d desactualizados, %d con cupos desactualizados y %d huérfanos reparados",
+					len(report.Missing), len(report.Stale), len(report.Seats), len(report.Orphans))
+			}
+		}
+	}
+}
+
+// LastReport devuelve el informe de la última reconciliación, si hubo alguna
+func (service Service) LastReport() (domain.DriftReport, bool) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.report == nil {
+		return domain.DriftReport{}, false
+	}
+	return *service.report, true
+}
+
+// Run ejecuta una reconciliación de forma sincrónica y devuelve su informe
+func (service Service) Run(ctx context.Context) (domain.DriftReport, error) {
+	if !service.running.TryLock() {
+		return domain.DriftReport{}, ErrAlreadyRunning
+	}
+	defer service.running.Unlock()
+
+	report := domain.DriftReport{
+		StartedAt: time.Now(),
+		Missing:   []int64{},
+		Stale:     []int64{},
+		Seats:     []int64{},
+		Orphans:   []int64{},
+	}
+	err := service.reconcile(ctx, &report)
+	now := time.Now()
+	report.FinishedAt = &now
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	service.mu.Lock()
+	service.report = &report
+	service.mu.Unlock()
+	return report, err
+}
+
+// reconcile lee primero el estado indexado y después recorre la API de cursos.
+// En ese orden, un curso creado entre ambas lecturas figura como faltante y se
+// vuelve a indexar, en lugar de quedar marcado como huérfano y eliminarse.
+func (service Service) reconcile(ctx context.Context, report *domain.DriftReport) error {
+	indexed, err := service.index.IndexedCourses(ctx)
+	if err != nil {
+		return err
+	}
+	report.Documents = len(indexed)
+
+	// Se recorre por clave y no por número de página: con páginas por número, un curso
+	// eliminado durante el recorrido corre a los siguientes y el primero de la próxima
+	// página nunca se ve
+	seen := make(map[int64]bool, len(indexed))
+	var lastID int64
+	for {
+		batch, _, err := service.coursesAPI.GetCoursesAfter(ctx, lastID, service.pageSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].CourseID
+		for _, course := range batch {
+			seen[course.CourseID] = true
+			doc, ok := indexed[course.CourseID]
+			switch {
+			case !ok:
+				report.Missing = append(report.Missing, course.CourseID)
+			case doc.ContentHash != course.ContentHash():
+				report.Stale = append(report.Stale, course.CourseID)
+			default:
+				// Los cupos cambian con cada inscripción y se comparan contra la API de inscripciones
+				count, err := service.inscriptions.CountByCourse(ctx, course.CourseID)
+				if err != nil {
+					return err
+				}
+				if doc.SeatsRemaining != course.WithSeats(count).SeatsRemaining {
+					report.Seats = append(report.Seats, course.CourseID)
+				}
+			}
+		}
+		report.Courses += len(batch)
+	}
+
+	for id := range indexed {
+		if !seen[id] {
+			report.Orphans = append(report.Orphans, id)
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i] < report.Orphans[j] })
+
+	return service.repair(ctx, report, indexed)
+}
+
+// repair vuelve a pedir cada curso faltante o desactualizado justo antes de
+// indexarlo, para no pisar con datos viejos un evento procesado mientras tanto.
+// Los huérfanos también se vuelven a consultar y solo se eliminan si la API de
+// cursos confirma que no existen. Los cursos conservan la versión de los cupos
+// indexada, así un evento de inscripciones atrasado no pisa los cupos recién contados.
+func (service Service) repair(ctx context.Context, report *domain.DriftReport, indexed map[int64]domain.IndexedCourse) error {
+	collection := service.index.Alias()
+	if err := service.confirmOrphans(ctx, report); err != nil {
+		return err
+	}
+
+	ids := append(append(append([]int64{}, report.Missing...), report.Stale...), report.Seats...)
+	batch := make([]domain.CourseUpdate, 0, len(ids))
+	for _, id := range ids {
+		course, err := service.coursesAPI.GetCourseByID(ctx, strconv.FormatInt(id, 10))
+		if err != nil {
+			// Si el curso se eliminó mientras tanto, lo quitará el evento de borrado
+			log.Printf("Reconciliación: no se pudo obtener el curso %d: %v", id, err)
+			continue
+		}
+		count, err := service.inscriptions.CountByCourse(ctx, id)
+		if err != nil {
+			return err
+		}
+		course = course.WithSeats(count)
+		course.SeatsVersion = indexed[id].SeatsVersion
+		batch = append(batch, course)
+	}
+
+	if len(batch) == 0 && len(report.Orphans) == 0 {
+		return nil
+	}
+	if len(batch) > 0 {
+		if err := service.index.IndexBatch(ctx, collection, batch); err != nil {
+			return err
+		}
+	}
+	if len(report.Orphans) > 0 {
+		if err := service.index.DeleteBatch(ctx, collection, report.Orphans); err != nil {
+			return err
+		}
+	}
+	if err := service.index.Commit(ctx, collection); err != nil {
+		return err
+	}
+	report.Repaired = true
+	return nil
+}
+
+// confirmOrphans deja en report.Orphans solo los cursos que la API de cursos
+// responde como inexistentes. Un curso que sí existe no se elimina del índice y
+// se vuelve a indexar como desactualizado; ante cualquier otro error se conserva hasta la
+// próxima reconciliación.
+func (service Service) confirmOrphans(ctx context.Context, report *domain.DriftReport) error {
+	confirmed := report.Orphans[:0]
+	for _, id := range report.Orphans {
+		_, err := service.coursesAPI.GetCourseByID(ctx, strconv.FormatInt(id, 10))
+		switch {
+		case errors.Is(err, domain.ErrCourseNotFound):
+			confirmed = append(confirmed, id)
+		case err == nil:
+			report.Stale = append(report.Stale, id)
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			log.Printf("Reconciliación: no se pudo verificar el curso huérfano %d: %v", id, err)
+		}
+	}
+	report.Orphans = confirmed
+	return nil
+}
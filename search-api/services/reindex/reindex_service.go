@@ -0,0 +1,278 @@
+package reindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	domain "search-api/domain/courses"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyRunning se devuelve al pedir una reindexación mientras otra está en curso
+var ErrAlreadyRunning = errors.New("ya hay una reindexación en curso")
+
+// Pasadas máximas para llevar a la colección nueva los cambios que los eventos
+// aplicaron en la colección actual durante una reindexación fresh
+const maxCatchUpPasses = 3
+
+// Indexer define las operaciones necesarias sobre SolR para reconstruir el índice
+type Indexer interface {
+	IndexBatch(ctx context.Context, collection string, batch []domain.CourseUpdate) error
+	DeleteBatch(ctx context.Context, collection string, ids []int64) error
+	IndexedCourses(ctx context.Context) (map[int64]domain.IndexedCourse, error)
+	Commit(ctx context.Context, collection string) error
+	CreateCollection(ctx context.Context, name string) error
+	SwapAlias(ctx context.Context, collection string) (string, error)
+	DeleteCollection(ctx context.Context, name string) error
+	Alias() string
+}
+
+// CoursesAPI define las consultas de cursos a la API de cursos
+type CoursesAPI interface {
+	GetCoursesAfter(ctx context.Context, afterID int64, pageSize int) ([]domain.CourseUpdate, int64, error)
+	GetCourseByID(ctx context.Context, id string) (domain.CourseUpdate, error)
+}
+
+// Inscriptions define la consulta de inscripciones usada para calcular los cupos disponibles
+type Inscriptions interface {
+	CountByCourse(ctx context.Context, courseID int64) (int, error)
+}
+
+// Service reconstruye el índice de SolR a partir de todos los cursos de la API de cursos
+type Service struct {
+	indexer      Indexer
+	coursesAPI   CoursesAPI
+	inscriptions Inscriptions
+	batchSize    int
+	mu           *sync.Mutex
+	status       *domain.ReindexStatus
+}
+
+// NewService crea una nueva instancia del servicio de reindexación
+func NewService(indexer Indexer, coursesAPI CoursesAPI, inscriptions Inscriptions, batchSize int) Service {
+	return Service{
+		indexer:      indexer,
+		coursesAPI:   coursesAPI,
+		inscriptions: inscriptions,
+		batchSize:    batchSize,
+		mu:           &sync.Mutex{},
+		status:       &domain.ReindexStatus{},
+	}
+}
+
+// Status devuelve el progreso de la reindexación en curso o de la última ejecutada
+func (service Service) Status() domain.ReindexStatus {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return *service.status
+}
+
+// Start lanza la reindexación en segundo plano y devuelve su estado inicial
+func (service Service) Start(fresh bool) (domain.ReindexStatus, error) {
+	if err := service.begin(fresh); err != nil {
+		return domain.ReindexStatus{}, err
+	}
+	go func() {
+		if err := service.run(context.Background(), fresh); err != nil {
+			log.Printf("Error en la reindexación: %v", err)
+		}
+	}()
+	return service.Status(), nil
+}
+
+// Run ejecuta la reindexación de forma sincrónica
+func (service Service) Run(ctx context.Context, fresh bool) error {
+	if err := service.begin(fresh); err != nil {
+		return err
+	}
+	return service.run(ctx, fresh)
+}
+
+func (service Service) begin(fresh bool) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	if service.status.Running {
+		return ErrAlreadyRunning
+	}
+	*service.status = domain.ReindexStatus{
+		Running:    true,
+		Fresh:      fresh,
+		Collection: service.indexer.Alias(),
+		StartedAt:  time.Now(),
+	}
+	return nil
+}
+
+// run recorre todas las páginas de cursos e indexa cada una como un lote. En modo
+// fresh indexa en una colección nueva y recién al final mueve el alias, por lo
+// que las búsquedas nunca ven un índice a medio construir. Mientras tanto los
+// eventos se siguen aplicando en la colección actual; antes de mover el alias se
+// vuelven a indexar en la nueva los cursos que cambiaron desde el inicio. Sin
+// fresh solo se agregan o reemplazan documentos; los cursos eliminados no se
+// quitan del índice.
+func (service Service) run(ctx context.Context, fresh bool) (err error) {
+	defer func() {
+		service.mu.Lock()
+		defer service.mu.Unlock()
+		now := time.Now()
+		service.status.Running = false
+		service.status.FinishedAt = &now
+		if err != nil {
+			service.status.Error = err.Error()
+		}
+	}()
+
+	// Estado de la colección actual al empezar, para detectar lo que cambie durante
+	// la reindexación. Sus versiones de cupos pasan a los cursos indexados: los
+	// cupos contados después son al menos tan nuevos.
+	var live map[int64]domain.IndexedCourse
+	collection := service.indexer.Alias()
+	if fresh {
+		live, err = service.indexer.IndexedCourses(ctx)
+		if err != nil {
+			return err
+		}
+		collection = fmt.Sprintf("%s_%d", service.indexer.Alias(), time.Now().Unix())
+		if err := service.indexer.CreateCollection(ctx, collection); err != nil {
+			return err
+		}
+		service.update(func(status *domain.ReindexStatus) { status.Collection = collection })
+	}
+
+	// Se recorre por clave y no por número de página: con páginas por número, un curso
+	// eliminado durante el recorrido corre a los siguientes y uno queda sin indexar
+	var lastID int64
+	for {
+		batch, remaining, err := service.coursesAPI.GetCoursesAfter(ctx, lastID, service.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if lastID == 0 {
+			service.update(func(status *domain.ReindexStatus) { status.Total = remaining })
+		}
+		lastID = batch[len(batch)-1].CourseID
+		if err := service.index(ctx, collection, batch, live); err != nil {
+			return err
+		}
+
+		service.update(func(status *domain.ReindexStatus) { status.Indexed += len(batch) })
+		status := service.Status()
+		log.Printf("Reindexación: %d/%d cursos indexados en %s", status.Indexed, status.Total, collection)
+	}
+
+	if fresh {
+		if err := service.catchUp(ctx, collection, live); err != nil {
+			return err
+		}
+	}
+
+	// Un único commit al final hace visibles todos los lotes a la vez
+	if err := service.indexer.Commit(ctx, collection); err != nil {
+		return err
+	}
+
+	if fresh {
+		previous, err := service.indexer.SwapAlias(ctx, collection)
+		if err != nil {
+			return err
+		}
+		log.Printf("Alias %s apuntando a %s", service.indexer.Alias(), collection)
+		if previous != "" && previous != collection {
+			if err := service.indexer.DeleteCollection(ctx, previous); err != nil {
+				log.Printf("No se pudo eliminar la colección anterior %s: %v", previous, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// index calcula los cupos disponibles de los cursos y los indexa en la colección,
+// con la versión de cupos que tenían en live
+func (service Service) index(ctx context.Context, collection string, batch []domain.CourseUpdate, live map[int64]domain.IndexedCourse) error {
+	for i, course := range batch {
+		count, err := service.inscriptions.CountByCourse(ctx, course.CourseID)
+		if err != nil {
+			return err
+		}
+		batch[i] = course.WithSeats(count)
+		batch[i].SeatsVersion = live[course.CourseID].SeatsVersion
+	}
+	return service.indexer.IndexBatch(ctx, collection, batch)
+}
+
+// catchUp compara la colección actual con su estado al empezar la reindexación y
+// vuelve a consultar e indexar en la colección nueva los cursos que los eventos
+// crearon, modificaron o eliminaron mientras tanto. Repite hasta que una pasada
+// no encuentre cambios; los eventos posteriores a la última pasada y anteriores
+// al cambio de alias los corrige la reconciliación.
+func (service Service) catchUp(ctx context.Context, collection string, live map[int64]domain.IndexedCourse) error {
+	for pass := 1; pass <= maxCatchUpPasses; pass++ {
+		current, err := service.indexer.IndexedCourses(ctx)
+		if err != nil {
+			return err
+		}
+		changed := changedCourses(live, current)
+		if len(changed) == 0 {
+			return nil
+		}
+		log.Printf("Reindexación: %d cursos cambiaron durante la reindexación (pasada %d)", len(changed), pass)
+
+		var batch []domain.CourseUpdate
+		var deleted []int64
+		for _, id := range changed {
+			course, err := service.coursesAPI.GetCourseByID(ctx, strconv.FormatInt(id, 10))
+			switch {
+			case errors.Is(err, domain.ErrCourseNotFound):
+				deleted = append(deleted, id)
+			case err != nil:
+				return err
+			default:
+				batch = append(batch, course)
+			}
+		}
+		if len(batch) > 0 {
+			if err := service.index(ctx, collection, batch, current); err != nil {
+				return err
+			}
+		}
+		if len(deleted) > 0 {
+			if err := service.indexer.DeleteBatch(ctx, collection, deleted); err != nil {
+				return err
+			}
+		}
+		live = current
+	}
+	return nil
+}
+
+// changedCourses devuelve los cursos que aparecen, desaparecen o cambian entre
+// dos lecturas del índice
+func changedCourses(before, after map[int64]domain.IndexedCourse) []int64 {
+	var changed []int64
+	for id, course := range after {
+		if previous, ok := before[id]; !ok || previous != course {
+			changed = append(changed, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i] < changed[j] })
+	return changed
+}
+
+func (service Service) update(apply func(status *domain.ReindexStatus)) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	apply(service.status)
+}
@@ -0,0 +1,138 @@
+package reindex
+
+import (
+	"context"
+	"reflect"
+	domain "search-api/domain/courses"
+	"strconv"
+	"testing"
+)
+
+// fakeIndexer guarda los documentos de cada colección. Al indexar en la colección
+// nueva ejecuta onIndex, que simula eventos aplicados en la actual.
+type fakeIndexer struct {
+	collections map[string]map[int64]domain.CourseUpdate
+	onIndex     func()
+}
+
+func newFakeIndexer(live ...domain.CourseUpdate) *fakeIndexer {
+	indexer := &fakeIndexer{collections: map[string]map[int64]domain.CourseUpdate{"courses": {}}}
+	for _, course := range live {
+		indexer.collections["courses"][course.CourseID] = course
+	}
+	return indexer
+}
+
+func (f *fakeIndexer) IndexBatch(ctx context.Context, collection string, batch []domain.CourseUpdate) error {
+	for _, course := range batch {
+		f.collections[collection][course.CourseID] = course
+	}
+	if f.onIndex != nil {
+		f.onIndex()
+		f.onIndex = nil
+	}
+	return nil
+}
+
+func (f *fakeIndexer) DeleteBatch(ctx context.Context, collection string, ids []int64) error {
+	for _, id := range ids {
+		delete(f.collections[collection], id)
+	}
+	return nil
+}
+
+func (f *fakeIndexer) IndexedCourses(ctx context.Context) (map[int64]domain.IndexedCourse, error) {
+	indexed := map[int64]domain.IndexedCourse{}
+	for id, course := range f.collections["courses"] {
+		indexed[id] = domain.IndexedCourse{
+			ContentHash:    course.ContentHash(),
+			SeatsRemaining: course.SeatsRemaining,
+			SeatsVersion:   course.SeatsVersion,
+		}
+	}
+	return indexed, nil
+}
+
+func (f *fakeIndexer) Commit(ctx context.Context, collection string) error { return nil }
+
+func (f *fakeIndexer) CreateCollection(ctx context.Context, name string) error {
+	f.collections[name] = map[int64]domain.CourseUpdate{}
+	return nil
+}
+
+func (f *fakeIndexer) SwapAlias(ctx context.Context, collection string) (string, error) {
+	f.collections["courses"] = f.collections[collection]
+	return "", nil
+}
+
+func (f *fakeIndexer) DeleteCollection(ctx context.Context, name string) error { return nil }
+
+func (f *fakeIndexer) Alias() string { return "courses" }
+
+// fakeCourses API de cursos en memoria, paginada por clave
+type fakeCourses map[int64]domain.CourseUpdate
+
+func (f fakeCourses) GetCoursesAfter(ctx context.Context, afterID int64, pageSize int) ([]domain.CourseUpdate, int64, error) {
+	var page []domain.CourseUpdate
+	var remaining int64
+	for id := afterID + 1; id <= 100; id++ {
+		if course, ok := f[id]; ok {
+			remaining++
+			if len(page) < pageSize {
+				page = append(page, course)
+			}
+		}
+	}
+	return page, remaining, nil
+}
+
+func (f fakeCourses) GetCourseByID(ctx context.Context, id string) (domain.CourseUpdate, error) {
+	courseID, _ := strconv.ParseInt(id, 10, 64)
+	course, ok := f[courseID]
+	if !ok {
+		return domain.CourseUpdate{}, domain.ErrCourseNotFound
+	}
+	return course, nil
+}
+
+type noInscriptions struct{}
+
+func (noInscriptions) CountByCourse(ctx context.Context, courseID int64) (int, error) { return 0, nil }
+
+func course(id int64, name string, version int64) domain.CourseUpdate {
+	return domain.CourseUpdate{CourseID: id, Name: name, Capacity: 10, Version: version}
+}
+
+// Los cursos que los eventos modifican, crean o eliminan en la colección actual
+// mientras se construye la nueva llegan a ella antes de mover el alias
+func TestFreshReindexCatchesUpWithLiveEvents(t *testing.T) {
+	api := fakeCourses{1: course(1, "Go", 1), 2: course(2, "Rust", 1), 3: course(3, "Python", 1)}
+	live := course(1, "Go", 1).WithSeats(0)
+	live.SeatsVersion = 4
+	indexer := newFakeIndexer(live, course(2, "Rust", 1).WithSeats(0), course(3, "Python", 1).WithSeats(0))
+	indexer.onIndex = func() {
+		// Eventos aplicados en la colección actual después de leer la primera página
+		api[2] = course(2, "Rust avanzado", 2)
+		indexer.collections["courses"][2] = api[2].WithSeats(0)
+		delete(api, 3)
+		delete(indexer.collections["courses"], 3)
+		api[4] = course(4, "Haskell", 1)
+		indexer.collections["courses"][4] = api[4].WithSeats(0)
+	}
+
+	service := NewService(indexer, api, noInscriptions{}, 10)
+	if err := service.Run(context.Background(), true); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	names := map[int64]string{}
+	for id, indexed := range indexer.collections["courses"] {
+		names[id] = indexed.Name
+	}
+	if want := map[int64]string{1: "Go", 2: "Rust avanzado", 4: "Haskell"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("cursos indexados = %v, want %v", names, want)
+	}
+	if version := indexer.collections["courses"][1].SeatsVersion; version != 4 {
+		t.Errorf("versión de cupos del curso 1 = %d, want 4", version)
+	}
+}
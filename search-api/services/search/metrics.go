@@ -0,0 +1,49 @@
+package search
+
+import (
+	domain "search-api/domain/courses"
+	"sync"
+	"time"
+)
+
+// Metrics acumula las métricas de rendimiento del indexador
+type Metrics struct {
+	mu      sync.Mutex
+	metrics domain.IndexerMetrics
+}
+
+// NewMetrics crea un acumulador de métricas vacío
+func NewMetrics() *Metrics {
+	return &Metrics{metrics: domain.IndexerMetrics{StartedAt: time.Now()}}
+}
+
+// record registra un lote procesado
+func (m *Metrics) record(events, indexed, deleted, failed, fetched, outdated int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.metrics.Batches++
+	m.metrics.Events += int64(events)
+	m.metrics.Indexed += int64(indexed)
+	m.metrics.Deleted += int64(deleted)
+	m.metrics.Failed += int64(failed)
+	m.metrics.Fetched += int64(fetched)
+	m.metrics.Outdated += int64(outdated)
+	m.metrics.LastBatchSize = events
+	m.metrics.LastBatchMillis = elapsed.Milliseconds()
+	m.metrics.LastBatchAt = &now
+}
+
+// Snapshot devuelve una copia de las métricas con los promedios calculados
+func (m *Metrics) Snapshot() domain.IndexerMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.metrics
+	if snapshot.Batches > 0 {
+		snapshot.AvgBatchSize = float64(snapshot.Events) / float64(snapshot.Batches)
+	}
+	if uptime := time.Since(snapshot.StartedAt).Seconds(); uptime > 0 {
+		snapshot.EventsPerSecond = float64(snapshot.Events) / uptime
+	}
+	return snapshot
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	domain "search-api/domain/courses"
+	"strings"
+	"unicode"
+)
+
+// Prefijo que filtra por categoría dentro del texto de búsqueda
+const categoryPrefix = "category:"
+
+// ParseQuery interpreta el texto de búsqueda según el mini lenguaje descrito en
+// domain.SearchQuery. Las comillas sin cerrar toman el resto del texto como frase.
+func ParseQuery(text string) domain.SearchQuery {
+	var query domain.SearchQuery
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		excluded := false
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			excluded = true
+			i++
+		}
+
+		category := false
+		if !excluded && strings.HasPrefix(strings.ToLower(string(runes[i:])), categoryPrefix) {
+			category = true
+			i += len(categoryPrefix)
+		}
+
+		var value string
+		quoted := i < len(runes) && runes[i] == '"'
+		if quoted {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			value = strings.TrimSpace(string(runes[i+1 : end]))
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			value = string(runes[i:end])
+			i = end
+		}
+		if value == "" {
+			continue
+		}
+
+		switch {
+		case category:
+			query.Categories = append(query.Categories, value)
+		case excluded:
+			query.Excluded = append(query.Excluded, value)
+		case quoted:
+			query.Phrases = append(query.Phrases, value)
+		default:
+			query.Terms = append(query.Terms, value)
+		}
+	}
+	return query
+}
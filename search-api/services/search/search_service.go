@@ -2,85 +2,383 @@ package search
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"events"
 	"fmt"
 	"log"
+	"search-api/domain/analytics"
 	domain "search-api/domain/courses"     // Alias para los tipos de dominio
 	repo "search-api/repositories/courses" // Alias para los repositorios
 	"strconv"
+	"time"
 )
 
+// Con menos resultados que este valor se ofrece la corrección "quisiste decir"
+const didYouMeanMaxHits = 3
+
+// ErrEmptyQuery se devuelve cuando la búsqueda no tiene ningún término ni filtro
+var ErrEmptyQuery = errors.New("la consulta de búsqueda está vacía")
+
+// ErrInvalidSort se devuelve cuando el orden pedido no es uno de los soportados
+var ErrInvalidSort = errors.New("orden inválido: se admite relevance, rating, newest o name")
+
+// Órdenes admitidos por el parámetro "sort"; vacío es la relevancia ponderada por calificación
+var validSorts = map[string]bool{
+	"":                   true,
+	domain.SortRelevance: true,
+	domain.SortRating:    true,
+	domain.SortNewest:    true,
+	domain.SortName:      true,
+}
+
+// ErrCourseNotFound se devuelve cuando el curso pedido no está indexado
+var ErrCourseNotFound = repo.ErrCourseNotFound
+
+// MaxPageSize es la cantidad máxima de resultados por página de búsqueda
+const MaxPageSize = 100
+
+// Posición máxima alcanzable paginando por offset; más allá hay que paginar por
+// cursor, porque SolR tiene que ordenar todos los resultados salteados
+const maxOffsetWindow = 10000
+
+// ErrInvalidPage se devuelve cuando los parámetros de paginación no son válidos
+var ErrInvalidPage = errors.New("paginación inválida")
+
+// ErrInvalidCursor se devuelve cuando el cursor no es uno devuelto por una búsqueda anterior
+var ErrInvalidCursor = repo.ErrInvalidCursor
+
 // Repository define las operaciones necesarias en el índice de SolR
 type Repository interface {
-	Index(ctx context.Context, course domain.CourseUpdate) (string, error)
-	Update(ctx context.Context, course domain.CourseUpdate) error
-	Delete(ctx context.Context, id string) error
-	Search(ctx context.Context, query string, limit int, offset int) ([]domain.CourseUpdate, error)
+	Apply(ctx context.Context, adds []domain.CourseUpdate, deletes []int64) error
+	Versions(ctx context.Context, ids []int64) (map[int64]domain.IndexedVersion, error)
+	SetSeats(ctx context.Context, seats map[int64]domain.Seats) error
+	Search(ctx context.Context, query domain.SearchQuery, filters domain.SearchFilters, sort string, page domain.SearchPage) (domain.SearchResponse, error)
+	Suggest(ctx context.Context, prefix string, limit int) ([]domain.Suggestion, error)
+	Similar(ctx context.Context, courseID int64, limit int) (domain.SimilarCoursesResponse, error)
+}
+
+// Inscriptions define la consulta de inscripciones usada para calcular los cupos disponibles
+type Inscriptions interface {
+	CountByCourse(ctx context.Context, courseID int64) (int, error)
+}
+
+// Analytics define el registro de las búsquedas realizadas por los usuarios
+type Analytics interface {
+	RecordSearch(event analytics.SearchEvent)
 }
 
 // Service representa el servicio de búsqueda
 type Service struct {
-	repository Repository
-	httpClient repo.HTTP // Cliente HTTP para interactuar con la API de Cursos
+	repository   Repository
+	httpClient   repo.HTTP // Cliente HTTP de la API de Cursos, solo para eventos sin snapshot
+	inscriptions Inscriptions
+	analytics    Analytics
+	metrics      *Metrics
 }
 
 // NewService crea una nueva instancia del servicio de búsqueda
-func NewService(repository Repository, httpClient repo.HTTP) Service {
+func NewService(repository Repository, httpClient repo.HTTP, inscriptions Inscriptions, analytics Analytics) Service {
 	return Service{
-		repository: repository,
-		httpClient: httpClient,
+		repository:   repository,
+		httpClient:   httpClient,
+		inscriptions: inscriptions,
+		analytics:    analytics,
+		metrics:      NewMetrics(),
 	}
 }
 
-// HandleCourseUpdate procesa las actualizaciones de cursos recibidas desde RabbitMQ
-func (service Service) HandleCourseUpdate(courseUpdate domain.CourseUpdate) {
+// HandleCourseUpdates procesa un lote de eventos de cursos recibidos desde RabbitMQ
+// y los aplica en SolR con una única solicitud. Devuelve un error por evento (nil
+// si se procesó); los eventos con error el consumidor los reintenta más tarde.
+func (service Service) HandleCourseUpdates(batch []events.CourseEvent) []error {
 	ctx := context.Background()
+	start := time.Now()
+	errs := make([]error, len(batch))
+
+	// El último evento de cada curso trae (o consulta) su estado más reciente, así
+	// que alcanza con ese; los anteriores del mismo curso comparten su resultado.
+	latest := make(map[int64]int, len(batch))
+	for i, event := range batch {
+		if j, ok := latest[event.CourseID]; ok && !supersedes(event, batch[j]) {
+			continue
+		}
+		latest[event.CourseID] = i
+	}
 
-	// Convertir CourseID a string
-	courseIDStr := strconv.FormatInt(courseUpdate.CourseID, 10)
+	var adds []domain.CourseUpdate
+	var deletes []int64
+	var applied []int
+	fetched := 0
+	for i, event := range batch {
+		if latest[event.CourseID] != i {
+			continue
+		}
+		switch event.Operation {
+		case events.OperationCreate, events.OperationUpdate:
+			if event.Course == nil {
+				fetched++
+			}
+			curso, err := service.courseFromEvent(ctx, event)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			adds = append(adds, curso)
+			continue
+		case events.OperationDelete:
+			// El curso ya no existe en la API de cursos, se elimina directamente del índice
+			deletes = append(deletes, event.CourseID)
+		default:
+			errs[i] = fmt.Errorf("operación desconocida: %s", event.Operation)
+			continue
+		}
+		applied = append(applied, i)
+	}
 
-	// Llamar a GetCourseByID y almacenar el resultado en 'curso'
-	curso, err := service.httpClient.GetCourseByID(ctx, courseIDStr)
+	adds, outdated, err := service.discardOutdated(ctx, adds)
 	if err != nil {
-		log.Printf("Error al obtener el curso (%s): %v", courseIDStr, err)
-		return // Salir de la función si hay un error
-	}
-
-	switch courseUpdate.Operation {
-	case "POST":
-		fmt.Println("Course update: ", courseUpdate)
-		// Indexar el nuevo curso en SolR
-		if _, err := service.repository.Index(ctx, curso); err != nil { // Usar 'curso' en lugar de 'courseUpdate'
-			log.Printf("Error al indexar el curso (%d): %v", curso.CourseID, err)
-		} else {
-			log.Printf("Curso indexado exitosamente: %d", curso.CourseID)
+		for _, curso := range adds {
+			errs[latest[curso.CourseID]] = err
 		}
+		adds = nil
+	}
+	for _, curso := range adds {
+		applied = append(applied, latest[curso.CourseID])
+	}
+
+	if err := service.repository.Apply(ctx, adds, deletes); err != nil {
+		err = fmt.Errorf("error al aplicar el lote en SolR: %w", err)
+		for _, i := range applied {
+			errs[i] = err
+		}
+		adds, deletes = nil, nil
+	}
+
+	failed := 0
+	for i, event := range batch {
+		errs[i] = errs[latest[event.CourseID]]
+		if errs[i] != nil {
+			failed++
+		}
+	}
+	service.metrics.record(len(batch), len(adds), len(deletes), failed, fetched, outdated, time.Since(start))
+	log.Printf("Lote de %d eventos aplicado: %d cursos indexados, %d eliminados, %d errores", len(batch), len(adds), len(deletes), failed)
+	return errs
+}
+
+// HandleInscriptionUpdates procesa un lote de eventos de inscripciones y actualiza
+// en el índice solo los cupos disponibles de sus cursos, que cada evento trae ya
+// calculados, sin volver a consultar las APIs. Los eventos sin cupos (anteriores
+// al campo o de cursos sin contador) se ignoran: la reconciliación corrige esos
+// cursos. Los relays publican en paralelo y los eventos fallidos vuelven por la
+// cola de reintentos, así que pueden llegar desordenados: gana la mayor versión
+// de los cupos y se descartan las menores a la indexada. Devuelve un error por
+// evento, nil si se procesó.
+func (service Service) HandleInscriptionUpdates(batch []events.InscriptionEvent) []error {
+	ctx := context.Background()
+	errs := make([]error, len(batch))
 
-	case "UPDATE":
-		// Actualizar el curso existente en SolR
-		if err := service.repository.Update(ctx, curso); err != nil { //
-			log.Printf("Error al actualizar el curso (%d): %v", courseUpdate.CourseID, err)
-		} else {
-			log.Printf("Curso actualizado exitosamente: %d", courseUpdate.CourseID)
+	seats := make(map[int64]domain.Seats, len(batch))
+	for _, event := range batch {
+		if event.SeatsRemaining == nil {
+			continue
+		}
+		if current, ok := seats[event.CourseID]; ok && event.SeatsVersion < current.Version {
+			continue
 		}
+		seats[event.CourseID] = domain.Seats{Remaining: *event.SeatsRemaining, Version: event.SeatsVersion}
+	}
+	if len(seats) == 0 {
+		return errs
+	}
 
-	case "DELETE":
-		// Eliminar el curso del índice de SolR
-		if err := service.repository.Delete(ctx, fmt.Sprintf("%d", courseUpdate.CourseID)); err != nil { // Convierte courseUpdate.CourseID a string
-			log.Printf("Error al eliminar el curso (%d): %v", courseUpdate.CourseID, err)
-		} else {
-			log.Printf("Curso eliminado exitosamente: %d", courseUpdate.CourseID)
+	// Solo se actualizan los cursos indexados, los demás los indexa su evento de creación
+	ids := make([]int64, 0, len(seats))
+	for id := range seats {
+		ids = append(ids, id)
+	}
+	indexed, err := service.repository.Versions(ctx, ids)
+	if err == nil {
+		for id, course := range seats {
+			version, ok := indexed[id]
+			if !ok || course.Version < version.Seats {
+				delete(seats, id)
+			}
 		}
+		err = service.repository.SetSeats(ctx, seats)
+	}
+	if err != nil {
+		err = fmt.Errorf("error al actualizar los cupos en SolR: %w", err)
+		for i, event := range batch {
+			if event.SeatsRemaining != nil {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+	log.Printf("Lote de %d eventos de inscripciones aplicado: cupos de %d cursos actualizados", len(batch), len(seats))
+	return errs
+}
 
-	default:
-		log.Printf("Operación desconocida: %s", courseUpdate.Operation)
+// supersedes indica si event, recibido después de previous, reemplaza su
+// resultado. La eliminación de un curso es definitiva y gana siempre. Si ambos
+// traen snapshot y la cola los entregó desordenados, gana el de mayor versión;
+// sin snapshot no hay versión que comparar y gana el último.
+func supersedes(event, previous events.CourseEvent) bool {
+	switch {
+	case previous.Operation == events.OperationDelete:
+		return false
+	case event.Operation == events.OperationDelete:
+		return true
+	case event.Course != nil && previous.Course != nil:
+		return event.Course.Version >= previous.Course.Version
 	}
+	return true
 }
 
-// Search busca cursos en SolR según el término de búsqueda, límite y desplazamiento
-func (service Service) Search(ctx context.Context, query string, limit int, offset int) ([]domain.CourseUpdate, error) {
-	results, err := service.repository.Search(ctx, query, limit, offset)
+// discardOutdated quita los cursos cuya versión es menor a la del documento ya
+// indexado: un snapshot reintentado o reenviado desde la DLQ no debe pisar uno
+// más nuevo. Devuelve los cursos a indexar y cuántos se descartaron. Los
+// documentos indexados antes de versionar los cursos tienen versión 0. Los cursos
+// conservan la versión de los cupos indexada, ya que sus cupos recién contados
+// son al menos tan nuevos como esa versión.
+func (service Service) discardOutdated(ctx context.Context, adds []domain.CourseUpdate) ([]domain.CourseUpdate, int, error) {
+	if len(adds) == 0 {
+		return adds, 0, nil
+	}
+	ids := make([]int64, 0, len(adds))
+	for _, curso := range adds {
+		ids = append(ids, curso.CourseID)
+	}
+	indexed, err := service.repository.Versions(ctx, ids)
+	if err != nil {
+		return adds, 0, fmt.Errorf("error al leer las versiones indexadas: %w", err)
+	}
+
+	current := adds[:0]
+	for _, curso := range adds {
+		version, ok := indexed[curso.CourseID]
+		if ok && curso.Version < version.Course {
+			log.Printf("Se descarta el curso %d en versión %d, el índice tiene la versión %d", curso.CourseID, curso.Version, version.Course)
+			continue
+		}
+		curso.SeatsVersion = version.Seats
+		current = append(current, curso)
+	}
+	return current, len(adds) - len(current), nil
+}
+
+// courseFromEvent arma el documento del curso a partir del snapshot del evento y
+// calcula sus cupos disponibles con las inscripciones. Los mensajes anteriores al
+// snapshot no lo traen, en ese caso se consulta el curso a la API de cursos.
+func (service Service) courseFromEvent(ctx context.Context, event events.CourseEvent) (domain.CourseUpdate, error) {
+	courseIDStr := strconv.FormatInt(event.CourseID, 10)
+	var curso domain.CourseUpdate
+	if event.Course != nil {
+		curso = domain.FromSnapshot(*event.Course)
+	} else {
+		var err error
+		curso, err = service.httpClient.GetCourseByID(ctx, courseIDStr)
+		if err != nil {
+			return domain.CourseUpdate{}, fmt.Errorf("error al obtener el curso (%s): %w", courseIDStr, err)
+		}
+	}
+
+	count, err := service.inscriptions.CountByCourse(ctx, curso.CourseID)
 	if err != nil {
-		return nil, fmt.Errorf("error en la búsqueda de cursos: %w", err)
+		return domain.CourseUpdate{}, fmt.Errorf("error al obtener las inscripciones del curso (%s): %w", courseIDStr, err)
+	}
+	return curso.WithSeats(count), nil
+}
+
+// Metrics devuelve las métricas de rendimiento del indexador
+func (service Service) Metrics() domain.IndexerMetrics {
+	return service.metrics.Snapshot()
+}
+
+// Search busca cursos en SolR según el texto de búsqueda (ver ParseQuery), los
+// filtros de las facetas, orden, límite y desplazamiento. Sin texto se listan
+// todos los cursos que cumplen los filtros.
+func (service Service) Search(ctx context.Context, text string, filters domain.SearchFilters, sort string, page domain.SearchPage) (domain.SearchResponse, error) {
+	query := ParseQuery(text)
+	if query.IsEmpty() && filters.IsEmpty() {
+		return domain.SearchResponse{}, ErrEmptyQuery
+	}
+	if !validSorts[sort] {
+		return domain.SearchResponse{}, ErrInvalidSort
+	}
+	if err := validatePage(page); err != nil {
+		return domain.SearchResponse{}, err
+	}
+	start := time.Now()
+	results, err := service.repository.Search(ctx, query, filters, sort, page)
+	if err != nil {
+		return domain.SearchResponse{}, fmt.Errorf("error en la búsqueda de cursos: %w", err)
+	}
+	if results.Total >= didYouMeanMaxHits {
+		results.DidYouMean = ""
+	}
+
+	// Solo la primera página cuenta como búsqueda; las siguientes conservan su identificador
+	results.SearchID = page.SearchID
+	if page.IsFirst() || results.SearchID == "" {
+		results.SearchID = newSearchID()
+	}
+	if page.IsFirst() {
+		service.analytics.RecordSearch(analytics.SearchEvent{
+			SearchID:      results.SearchID,
+			Query:         text,
+			Filters:       filters,
+			Sort:          sort,
+			Hits:          results.Total,
+			LatencyMillis: float64(time.Since(start).Microseconds()) / 1000,
+			Timestamp:     start,
+		})
 	}
 	return results, nil
 }
+
+// newSearchID genera un identificador aleatorio para una búsqueda
+func newSearchID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(id)
+}
+
+// validatePage controla el tamaño de página y que offset y cursor no se combinen
+func validatePage(page domain.SearchPage) error {
+	switch {
+	case page.Limit < 1 || page.Limit > MaxPageSize:
+		return fmt.Errorf("%w: limit debe estar entre 1 y %d", ErrInvalidPage, MaxPageSize)
+	case page.Offset < 0:
+		return fmt.Errorf("%w: offset no puede ser negativo", ErrInvalidPage)
+	case page.Cursor != "" && page.Offset > 0:
+		return fmt.Errorf("%w: offset y cursor no pueden usarse juntos", ErrInvalidPage)
+	case page.Offset+page.Limit > maxOffsetWindow:
+		return fmt.Errorf("%w: para ver más allá de los primeros %d resultados use cursor=%s",
+			ErrInvalidPage, maxOffsetWindow, domain.CursorStart)
+	}
+	return nil
+}
+
+// Suggest devuelve nombres de cursos y categorías que completan el prefijo
+func (service Service) Suggest(ctx context.Context, prefix string, limit int) ([]domain.Suggestion, error) {
+	suggestions, err := service.repository.Suggest(ctx, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener sugerencias: %w", err)
+	}
+	return suggestions, nil
+}
+
+// Similar devuelve cursos parecidos al indicado que todavía tienen cupos disponibles
+func (service Service) Similar(ctx context.Context, courseID int64, limit int) (domain.SimilarCoursesResponse, error) {
+	similar, err := service.repository.Similar(ctx, courseID, limit)
+	if err != nil {
+		return domain.SimilarCoursesResponse{}, fmt.Errorf("error al buscar cursos similares: %w", err)
+	}
+	return similar, nil
+}
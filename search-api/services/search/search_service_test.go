@@ -0,0 +1,385 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"events"
+	"fmt"
+	"reflect"
+	"search-api/domain/analytics"
+	domain "search-api/domain/courses"
+	repo "search-api/repositories/courses"
+	"testing"
+	"time"
+)
+
+// fakeInscriptions devuelve la cantidad de inscripciones configurada para cada curso
+type fakeInscriptions struct {
+	counts map[int64]int
+	failed map[int64]bool
+}
+
+func (f fakeInscriptions) CountByCourse(ctx context.Context, courseID int64) (int, error) {
+	if f.failed[courseID] {
+		return 0, errors.New("inscriptions-api no disponible")
+	}
+	return f.counts[courseID], nil
+}
+
+// fakeAnalytics guarda las búsquedas registradas
+type fakeAnalytics struct {
+	searches *[]analytics.SearchEvent
+}
+
+func (f fakeAnalytics) RecordSearch(event analytics.SearchEvent) {
+	*f.searches = append(*f.searches, event)
+}
+
+type fixture struct {
+	service      Service
+	backend      repo.Memory
+	inscriptions fakeInscriptions
+	searches     *[]analytics.SearchEvent
+}
+
+func newFixture() fixture {
+	f := fixture{
+		backend:      repo.NewMemory(),
+		inscriptions: fakeInscriptions{counts: map[int64]int{}, failed: map[int64]bool{}},
+		searches:     &[]analytics.SearchEvent{},
+	}
+	f.service = NewService(f.backend, repo.HTTP{}, f.inscriptions, fakeAnalytics{searches: f.searches})
+	return f
+}
+
+func snapshot(id int64, name, category string, capacity int) *events.CourseSnapshot {
+	return &events.CourseSnapshot{
+		ID:          id,
+		Name:        name,
+		Description: "Curso de " + name,
+		Category:    category,
+		Duration:    "4 semanas",
+		Capacity:    capacity,
+		Rating:      4,
+	}
+}
+
+func courseEvent(operation events.Operation, courseID int64, course *events.CourseSnapshot) events.CourseEvent {
+	return events.CourseEvent{
+		EventID:       "evt",
+		SchemaVersion: events.SchemaVersion,
+		Operation:     operation,
+		CourseID:      courseID,
+		Timestamp:     time.Now(),
+		Course:        course,
+	}
+}
+
+func firstPage() domain.SearchPage {
+	return domain.SearchPage{Limit: 10}
+}
+
+// search devuelve los IDs de los cursos encontrados, en orden
+func (f fixture) search(t *testing.T, text string) []int64 {
+	t.Helper()
+	results, err := f.service.Search(context.Background(), text, domain.SearchFilters{}, domain.SortName, firstPage())
+	if err != nil {
+		t.Fatalf("Search(%q): %v", text, err)
+	}
+	ids := make([]int64, 0, len(results.Results))
+	for _, hit := range results.Results {
+		ids = append(ids, hit.CourseID)
+	}
+	return ids
+}
+
+func (f fixture) apply(t *testing.T, batch ...events.CourseEvent) {
+	t.Helper()
+	for i, err := range f.service.HandleCourseUpdates(batch) {
+		if err != nil {
+			t.Fatalf("evento %d: %v", i, err)
+		}
+	}
+}
+
+func TestHandleCourseUpdatesIndexesSnapshots(t *testing.T) {
+	f := newFixture()
+	f.inscriptions.counts[1] = 3
+	f.apply(t,
+		courseEvent(events.OperationCreate, 1, snapshot(1, "Go avanzado", "programacion", 10)),
+		courseEvent(events.OperationCreate, 2, snapshot(2, "Go inicial", "programacion", 5)),
+	)
+
+	results, err := f.service.Search(context.Background(), "go", domain.SearchFilters{}, domain.SortName, firstPage())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if results.Total != 2 {
+		t.Fatalf("Total = %d, want 2", results.Total)
+	}
+	seats := map[int64]int{}
+	for _, hit := range results.Results {
+		seats[hit.CourseID] = hit.SeatsRemaining
+	}
+	if seats[1] != 7 || seats[2] != 5 {
+		t.Errorf("SeatsRemaining = %v, want map[1:7 2:5]", seats)
+	}
+}
+
+func TestHandleCourseUpdatesAppliesLatestEventPerCourse(t *testing.T) {
+	f := newFixture()
+	f.apply(t, courseEvent(events.OperationCreate, 1, snapshot(1, "Python", "datos", 10)))
+
+	// En un mismo lote solo cuenta el último evento de cada curso
+	f.apply(t,
+		courseEvent(events.OperationUpdate, 1, snapshot(1, "Rust", "sistemas", 10)),
+		courseEvent(events.OperationDelete, 1, nil),
+		courseEvent(events.OperationCreate, 2, snapshot(2, "Rust embebido", "sistemas", 10)),
+	)
+
+	if ids := f.search(t, "rust"); len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("search rust = %v, want [2]", ids)
+	}
+	if ids := f.search(t, "python"); len(ids) != 0 {
+		t.Errorf("search python = %v, want []", ids)
+	}
+}
+
+// Una eliminación gana aunque los eventos anteriores del lote traigan snapshots
+// versionados, y un snapshot viejo no pisa a uno más nuevo del mismo lote
+func TestHandleCourseUpdatesVersionedBatch(t *testing.T) {
+	f := newFixture()
+	versioned := func(id int64, name string, version int64) *events.CourseSnapshot {
+		course := snapshot(id, name, "sistemas", 10)
+		course.Version = version
+		return course
+	}
+	f.apply(t,
+		courseEvent(events.OperationCreate, 1, versioned(1, "Python", 1)),
+		courseEvent(events.OperationUpdate, 1, versioned(1, "Rust", 2)),
+		courseEvent(events.OperationDelete, 1, nil),
+		courseEvent(events.OperationUpdate, 1, versioned(1, "Rust", 2)),
+		courseEvent(events.OperationUpdate, 2, versioned(2, "Go", 3)),
+		courseEvent(events.OperationUpdate, 2, versioned(2, "Haskell", 2)),
+	)
+
+	if ids := f.search(t, "rust"); len(ids) != 0 {
+		t.Errorf("search rust = %v, want []", ids)
+	}
+	if ids := f.search(t, "go"); len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("search go = %v, want [2]", ids)
+	}
+	if ids := f.search(t, "haskell"); len(ids) != 0 {
+		t.Errorf("search haskell = %v, want []", ids)
+	}
+}
+
+func TestHandleCourseUpdatesReportsErrorsPerCourse(t *testing.T) {
+	f := newFixture()
+	f.inscriptions.failed[2] = true
+	errs := f.service.HandleCourseUpdates([]events.CourseEvent{
+		courseEvent(events.OperationCreate, 1, snapshot(1, "Kotlin", "mobile", 10)),
+		courseEvent(events.OperationCreate, 2, snapshot(2, "Swift", "mobile", 10)),
+		courseEvent(events.OperationUpdate, 2, snapshot(2, "Swift UI", "mobile", 10)),
+	})
+
+	if errs[0] != nil {
+		t.Errorf("evento 0: %v, want nil", errs[0])
+	}
+	// Los eventos anteriores del mismo curso comparten el resultado del último
+	if errs[1] == nil || errs[2] == nil {
+		t.Errorf("errores = %v, want error en los eventos del curso 2", errs)
+	}
+	if ids := f.search(t, "mobile"); len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("search mobile = %v, want [1]", ids)
+	}
+	if got := f.service.Metrics().Failed; got != 2 {
+		t.Errorf("Failed = %d, want 2", got)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	f := newFixture()
+	tests := []struct {
+		name string
+		text string
+		sort string
+		page domain.SearchPage
+		want error
+	}{
+		{"consulta vacía", "  ", "", firstPage(), ErrEmptyQuery},
+		{"orden desconocido", "go", "price", firstPage(), ErrInvalidSort},
+		{"límite cero", "go", "", domain.SearchPage{}, ErrInvalidPage},
+		{"límite excesivo", "go", "", domain.SearchPage{Limit: MaxPageSize + 1}, ErrInvalidPage},
+		{"offset y cursor", "go", "", domain.SearchPage{Limit: 10, Offset: 10, Cursor: domain.CursorStart}, ErrInvalidPage},
+		{"offset profundo", "go", "", domain.SearchPage{Limit: 10, Offset: maxOffsetWindow}, ErrInvalidPage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.service.Search(context.Background(), tt.text, domain.SearchFilters{}, tt.sort, tt.page)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Search error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRecordsOnlyFirstPage(t *testing.T) {
+	f := newFixture()
+	for id, name := range map[int64]string{1: "Go uno", 2: "Go dos", 3: "Go tres"} {
+		f.apply(t, courseEvent(events.OperationCreate, id, snapshot(id, name, "programacion", 10)))
+	}
+
+	first, err := f.service.Search(context.Background(), "go", domain.SearchFilters{}, "", domain.SearchPage{Limit: 2})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	second, err := f.service.Search(context.Background(), "go", domain.SearchFilters{}, "",
+		domain.SearchPage{Limit: 2, Offset: 2, SearchID: first.SearchID})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	if len(*f.searches) != 1 {
+		t.Fatalf("búsquedas registradas = %d, want 1", len(*f.searches))
+	}
+	if recorded := (*f.searches)[0]; recorded.SearchID != first.SearchID || recorded.Hits != 3 {
+		t.Errorf("búsqueda registrada = %+v, want SearchID %s con 3 resultados", recorded, first.SearchID)
+	}
+	if second.SearchID != first.SearchID {
+		t.Errorf("SearchID de la segunda página = %q, want %q", second.SearchID, first.SearchID)
+	}
+	if len(first.Results)+len(second.Results) != 3 {
+		t.Errorf("resultados = %d + %d, want 3", len(first.Results), len(second.Results))
+	}
+}
+
+func TestSimilarSkipsFullCourses(t *testing.T) {
+	f := newFixture()
+	f.inscriptions.counts[3] = 10
+	f.apply(t,
+		courseEvent(events.OperationCreate, 1, snapshot(1, "Bases de datos relacionales", "datos", 10)),
+		courseEvent(events.OperationCreate, 2, snapshot(2, "Bases de datos documentales", "datos", 10)),
+		courseEvent(events.OperationCreate, 3, snapshot(3, "Bases de datos en grafo", "datos", 10)),
+	)
+
+	similar, err := f.service.Similar(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("Similar: %v", err)
+	}
+	if len(similar.Results) != 1 || similar.Results[0].CourseID != 2 {
+		t.Errorf("Similar = %+v, want solo el curso 2", similar.Results)
+	}
+
+	if _, err := f.service.Similar(context.Background(), 99, 5); !errors.Is(err, ErrCourseNotFound) {
+		t.Errorf("Similar de un curso inexistente: error = %v, want %v", err, ErrCourseNotFound)
+	}
+}
+
+func TestHandleCourseUpdatesSkipsOutdatedSnapshots(t *testing.T) {
+	f := newFixture()
+	versioned := func(version int64, name string) *events.CourseSnapshot {
+		course := snapshot(1, name, "programacion", 10)
+		course.Version = version
+		return course
+	}
+	f.apply(t, courseEvent(events.OperationUpdate, 1, versioned(3, "Go v3")))
+
+	// Un reintento de la versión 2 llega después de indexada la 3
+	f.apply(t, courseEvent(events.OperationUpdate, 1, versioned(2, "Go v2")))
+	if ids := f.search(t, "v3"); len(ids) != 1 {
+		t.Errorf("search v3 = %v, want [1]", ids)
+	}
+	if got := f.service.Metrics().Outdated; got != 1 {
+		t.Errorf("Outdated = %d, want 1", got)
+	}
+
+	// Dentro de un lote gana la versión más alta aunque llegue antes
+	f.apply(t,
+		courseEvent(events.OperationUpdate, 1, versioned(5, "Go v5")),
+		courseEvent(events.OperationUpdate, 1, versioned(4, "Go v4")),
+	)
+	if ids := f.search(t, "v5"); len(ids) != 1 {
+		t.Errorf("search v5 = %v, want [1]", ids)
+	}
+	if ids := f.search(t, "v4"); len(ids) != 0 {
+		t.Errorf("search v4 = %v, want []", ids)
+	}
+}
+
+func inscriptionEvent(courseID int64, seats *int, version int64) events.InscriptionEvent {
+	return events.InscriptionEvent{
+		EventID:        fmt.Sprintf("i-%d-%d", courseID, version),
+		SchemaVersion:  events.InscriptionSchemaVersion,
+		Type:           events.InscriptionCreated,
+		InscriptionID:  1,
+		UserID:         1,
+		CourseID:       courseID,
+		SeatsRemaining: seats,
+		SeatsVersion:   version,
+	}
+}
+
+func (f fixture) applyInscriptions(t *testing.T, batch ...events.InscriptionEvent) {
+	t.Helper()
+	for i, err := range f.service.HandleInscriptionUpdates(batch) {
+		if err != nil {
+			t.Fatalf("evento %d: %v", i, err)
+		}
+	}
+}
+
+// seats devuelve los cupos disponibles de los cursos que encuentra la búsqueda
+func (f fixture) seats(t *testing.T, text string) map[int64]int {
+	t.Helper()
+	results, err := f.service.Search(context.Background(), text, domain.SearchFilters{}, domain.SortName, firstPage())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	seats := map[int64]int{}
+	for _, hit := range results.Results {
+		seats[hit.CourseID] = hit.SeatsRemaining
+	}
+	return seats
+}
+
+// Los cupos de los eventos de inscripciones se aplican sin consultar las
+// inscripciones; gana la mayor versión de cada curso y se ignoran los eventos sin
+// cupos y los cursos que no están indexados
+func TestHandleInscriptionUpdatesSetsSeats(t *testing.T) {
+	f := newFixture()
+	f.apply(t,
+		courseEvent(events.OperationCreate, 1, snapshot(1, "Go avanzado", "programacion", 10)),
+		courseEvent(events.OperationCreate, 2, snapshot(2, "Go inicial", "programacion", 5)),
+	)
+	// Si se consultaran las inscripciones el curso 1 quedaría sin cupos
+	f.inscriptions.counts[1] = 10
+
+	four, three, zero := 4, 3, 0
+	f.applyInscriptions(t,
+		inscriptionEvent(1, &three, 2),
+		inscriptionEvent(1, &four, 1),
+		inscriptionEvent(2, nil, 0),
+		inscriptionEvent(3, &zero, 1),
+	)
+	if seats, want := f.seats(t, "go"), map[int64]int{1: 3, 2: 5}; !reflect.DeepEqual(seats, want) {
+		t.Errorf("SeatsRemaining = %v, want %v", seats, want)
+	}
+}
+
+// Un evento reintentado con una versión de cupos menor a la indexada no pisa los
+// cupos, tampoco después de volver a indexar el curso
+func TestHandleInscriptionUpdatesSkipsOutdatedSeats(t *testing.T) {
+	f := newFixture()
+	f.apply(t, courseEvent(events.OperationCreate, 1, snapshot(1, "Go avanzado", "programacion", 10)))
+
+	two, seven := 2, 7
+	f.applyInscriptions(t, inscriptionEvent(1, &two, 8))
+	f.inscriptions.counts[1] = 8
+	f.apply(t, courseEvent(events.OperationUpdate, 1, snapshot(1, "Go avanzado", "programacion", 10)))
+	f.applyInscriptions(t, inscriptionEvent(1, &seven, 3))
+
+	if seats, want := f.seats(t, "go"), map[int64]int{1: 2}; !reflect.DeepEqual(seats, want) {
+		t.Errorf("SeatsRemaining = %v, want %v", seats, want)
+	}
+}